@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// percent of english text made up by each letter
+var englishLetterFreq = map[string]float64{
+	"a": 8.167, "b": 1.492, "c": 2.782, "d": 4.253, "e": 12.702, "f": 2.228,
+	"g": 2.015, "h": 6.094, "i": 6.966, "j": 0.153, "k": 0.772, "l": 4.025,
+	"m": 2.406, "n": 6.749, "o": 7.507, "p": 1.929, "q": 0.095, "r": 5.987,
+	"s": 6.327, "t": 9.056, "u": 2.758, "v": 0.978, "w": 2.360, "x": 0.150,
+	"y": 1.974, "z": 0.074,
+}
+
+// the most common english letter pairs and triples, as a percent of all
+// pairs/triples
+var englishBigramFreq = map[string]float64{
+	"th": 3.56, "he": 3.07, "in": 2.43, "er": 2.05, "an": 1.99, "re": 1.85,
+	"on": 1.76, "at": 1.49, "en": 1.45, "nd": 1.35, "ti": 1.34, "es": 1.34,
+	"or": 1.28, "te": 1.20, "of": 1.17, "ed": 1.17, "is": 1.13, "it": 1.12,
+	"al": 1.09, "ar": 1.07,
+}
+
+var englishTrigramFreq = map[string]float64{
+	"the": 1.81, "and": 0.73, "ing": 0.72, "ent": 0.42, "ion": 0.42,
+	"her": 0.36, "for": 0.34, "tha": 0.33, "nth": 0.33, "int": 0.32,
+	"ere": 0.31, "tio": 0.31, "ter": 0.30, "est": 0.28, "ers": 0.28,
+}
+
+const (
+	chartTopN      = 10
+	chartHeight    = 150
+	chartLabelRoom = 20
+)
+
+// Analysis is the frequency breakdown of a piece of text. Symbols counts
+// every character that isn't a space, so pages whose maps use digits or
+// punctuation get a chart too.
+type Analysis struct {
+	Input    string
+	Symbols  int
+	Letter   *FreqChart
+	Bigrams  *FreqChart
+	Trigrams *FreqChart
+}
+
+// FreqChart is a bar chart laid out ready to be drawn as SVG.
+type FreqChart struct {
+	Width  int
+	Height int
+	LabelY int
+	Bars   []ChartBar
+}
+
+// ChartBar is a single bar in a FreqChart. Reference bars are the english
+// numbers, the rest come from the text being analyzed.
+type ChartBar struct {
+	Label     string
+	Title     string
+	X         int
+	Y         int
+	Width     int
+	Height    int
+	LabelX    int
+	Reference bool
+}
+
+type freqCount struct {
+	gram    string
+	percent float64
+}
+
+type freqPair struct {
+	observed freqCount
+	english  freqCount
+}
+
+// words splits text into lower case runs of letters
+func words(input string) []string {
+	return strings.FieldsFunc(strings.ToLower(input), func(char rune) bool {
+		return !unicode.IsLetter(char)
+	})
+}
+
+// countGrams counts every run of n letters inside a word and returns them
+// as a percent of all the runs, most common first
+func countGrams(input string, n int) ([]freqCount, int) {
+	return countRuns(words(input), n)
+}
+
+// countSymbolGrams is countGrams for ciphertext. A page's map can use any
+// symbol, so everything but spaces is counted.
+func countSymbolGrams(input string, n int) ([]freqCount, int) {
+	return countRuns(strings.Fields(strings.ToLower(input)), n)
+}
+
+func countRuns(words []string, n int) ([]freqCount, int) {
+	counts := make(map[string]int)
+	total := 0
+	for _, word := range words {
+		chars := []rune(word)
+		for i := 0; i+n <= len(chars); i++ {
+			counts[string(chars[i:i+n])]++
+			total++
+		}
+	}
+
+	grams := []freqCount{}
+	for gram, count := range counts {
+		grams = append(grams, freqCount{gram, float64(count) * 100 / float64(total)})
+	}
+	sortCounts(grams)
+	return grams, total
+}
+
+func sortCounts(grams []freqCount) {
+	sort.Slice(grams, func(i, j int) bool {
+		if grams[i].percent == grams[j].percent {
+			return grams[i].gram < grams[j].gram
+		}
+		return grams[i].percent > grams[j].percent
+	})
+}
+
+func topCounts(freqs map[string]float64) []freqCount {
+	grams := []freqCount{}
+	for gram, percent := range freqs {
+		grams = append(grams, freqCount{gram, percent})
+	}
+	sortCounts(grams)
+	return grams
+}
+
+func analyzeText(input string) *Analysis {
+	letters, total := countSymbolGrams(input, 1)
+	if total == 0 {
+		return nil
+	}
+
+	// everything gets lined up by rank, which is what you actually compare
+	// when guessing at a substitution. The text's symbols and english are
+	// two separate series, so a page whose cipher uses digits or
+	// punctuation still gets a bar for every symbol.
+	rankPairs := func(observed []freqCount, english map[string]float64, limit int) []freqPair {
+		if len(observed) == 0 {
+			return nil
+		}
+		reference := topCounts(english)
+		if limit == 0 {
+			limit = len(observed)
+			if len(reference) > limit {
+				limit = len(reference)
+			}
+		}
+		pairs := []freqPair{}
+		for i := 0; i < limit && (i < len(observed) || i < len(reference)); i++ {
+			pair := freqPair{}
+			if i < len(observed) {
+				pair.observed = observed[i]
+			}
+			if i < len(reference) {
+				pair.english = reference[i]
+			}
+			pairs = append(pairs, pair)
+		}
+		return pairs
+	}
+	bigrams, _ := countSymbolGrams(input, 2)
+	trigrams, _ := countSymbolGrams(input, 3)
+
+	return &Analysis{
+		Input:    input,
+		Symbols:  total,
+		Letter:   buildChart(rankPairs(letters, englishLetterFreq, 0), 10),
+		Bigrams:  buildChart(rankPairs(bigrams, englishBigramFreq, chartTopN), 22),
+		Trigrams: buildChart(rankPairs(trigrams, englishTrigramFreq, chartTopN), 22),
+	}
+}
+
+// buildChart draws the text's bar and the english bar next to each other
+// for every pair. Bars are scaled so the tallest one fills the chart.
+func buildChart(pairs []freqPair, barWidth int) *FreqChart {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	max := 0.0
+	for _, pair := range pairs {
+		if pair.observed.percent > max {
+			max = pair.observed.percent
+		}
+		if pair.english.percent > max {
+			max = pair.english.percent
+		}
+	}
+
+	groupWidth := barWidth*2 + 8
+	chart := &FreqChart{
+		Width:  len(pairs) * groupWidth,
+		Height: chartHeight + chartLabelRoom,
+		LabelY: chartHeight + 14,
+	}
+	for i, pair := range pairs {
+		x := i * groupWidth
+		for j, count := range []freqCount{pair.observed, pair.english} {
+			// one series can run out before the other
+			if count.gram == "" {
+				continue
+			}
+			height := int(count.percent / max * chartHeight)
+			bar := ChartBar{
+				Title:     fmt.Sprintf("%s: %.2f%%", count.gram, count.percent),
+				X:         x + j*barWidth,
+				Y:         chartHeight - height,
+				Width:     barWidth - 1,
+				Height:    height,
+				Label:     count.gram,
+				LabelX:    x + j*barWidth + barWidth/2,
+				Reference: j == 1,
+			}
+			chart.Bars = append(chart.Bars, bar)
+		}
+	}
+	return chart
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCountGrams(t *testing.T) {
+	letters, total := countGrams("Hello, hello!", 1)
+	if total != 10 {
+		t.Errorf("countGrams() expected 10 letters, got: %v", total)
+	}
+	if letters[0].gram != "l" || letters[0].percent != 40 {
+		t.Errorf("countGrams() expected l at 40%%, got: %s at %v", letters[0].gram, letters[0].percent)
+	}
+
+	// pairs don't cross from one word into the next
+	bigrams, total := countGrams("ab cd", 2)
+	if total != 2 || len(bigrams) != 2 {
+		t.Errorf("countGrams() expected 2 pairs, got: %v %v", total, bigrams)
+	}
+
+	// a page's map can use any symbol, so punctuation and digits count
+	symbols, total := countSymbolGrams("!@# !!7", 1)
+	if total != 6 || symbols[0].gram != "!" || symbols[0].percent != 50 {
+		t.Errorf("countSymbolGrams() expected ! at 50%% of 6 symbols, got: %v %v", total, symbols)
+	}
+}
+
+func TestAnalyzeText(t *testing.T) {
+	if analyzeText(" \n ") != nil {
+		t.Errorf("analyzeText() expected nothing back when there are no symbols")
+	}
+
+	analysis := analyzeText("the thin thing")
+	if analysis.Symbols != 12 {
+		t.Errorf("analyzeText() expected 12 symbols, got: %v", analysis.Symbols)
+	}
+	// 6 different letters in the text and all 26 english ones
+	if len(analysis.Letter.Bars) != 32 {
+		t.Errorf("analyzeText() expected 32 letter bars, got: %v", len(analysis.Letter.Bars))
+	}
+	if analysis.Letter.Bars[0].Label != "h" || analysis.Letter.Bars[1].Label != "e" || !analysis.Letter.Bars[1].Reference {
+		t.Errorf("analyzeText() expected h next to english e, got: %v", analysis.Letter.Bars[:2])
+	}
+
+	// th is the top pair in both the text and english
+	top := analysis.Bigrams.Bars
+	if top[0].Label != "th" || top[1].Label != "th" || !top[1].Reference {
+		t.Errorf("analyzeText() expected th first, got: %s and %s", top[0].Label, top[1].Label)
+	}
+	if top[0].Height != chartHeight {
+		t.Errorf("analyzeText() expected the tallest bar to fill the chart, got: %v", top[0].Height)
+	}
+
+	// a map made of symbols still gets analyzed, next to english letters
+	analysis = analyzeText("!@# !#%")
+	if analysis == nil || analysis.Letter.Bars[0].Label != "!" || analysis.Letter.Bars[0].Reference {
+		t.Fatalf("analyzeText() expected ! as the top symbol, got: %v", analysis)
+	}
+	if bars := analysis.Letter.Bars; !bars[len(bars)-1].Reference || bars[len(bars)-1].Label != "z" {
+		t.Errorf("analyzeText() expected english to carry on to z, got: %v", bars[len(bars)-1])
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+const backupFormat = "ecc-backup"
+
+// sqliteHeader is how every sqlite database file starts, it's how restore
+// tells a snapshot from a json dump
+const sqliteHeader = "SQLite format 3\x00"
+
+// BackupDump is the portable copy of the database. Rows are kept as plain
+// values so it can be read back into any database the migrations run on.
+type BackupDump struct {
+	Format        string                 `json:"format"`
+	SchemaVersion int                    `json:"schemaVersion"`
+	CreatedAt     string                 `json:"createdAt"`
+	Tables        map[string]*BackupData `json:"tables"`
+}
+
+// BackupData is every row of one table, in column order
+type BackupData struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+var sqlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// backupSnapshot writes a consistent copy of the whole database to a new
+// file. VACUUM INTO won't overwrite a file that's already there.
+func backupSnapshot(db *sql.DB, dest string) error {
+	_, err := db.Exec("vacuum into ?", dest)
+	return err
+}
+
+// backupTables is every table except the migration bookkeeping, which a
+// restore makes for itself
+func backupTables(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("select name from sqlite_master where type = 'table' and name not like 'sqlite_%' and name != 'schema_version' order by name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+func dumpTable(db *sql.DB, table string) (*BackupData, error) {
+	rows, err := db.Query("select * from " + table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	data := &BackupData{Columns: columns, Rows: [][]interface{}{}}
+	for rows.Next() {
+		row := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		// everything is stored as text, but the driver can hand it back
+		// as bytes, which json would turn into base64
+		for i, value := range row {
+			if b, ok := value.([]byte); ok {
+				row[i] = string(b)
+			}
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	return data, rows.Err()
+}
+
+func dumpDB(db *sql.DB, w io.Writer) error {
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	tables, err := backupTables(db)
+	if err != nil {
+		return err
+	}
+
+	dump := BackupDump{
+		Format:        backupFormat,
+		SchemaVersion: version,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		Tables:        make(map[string]*BackupData),
+	}
+	for _, table := range tables {
+		data, err := dumpTable(db, table)
+		if err != nil {
+			return fmt.Errorf("unable to dump %s: %w", table, err)
+		}
+		dump.Tables[table] = data
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(dump)
+}
+
+func readDump(r io.Reader) (*BackupDump, error) {
+	decoder := json.NewDecoder(r)
+	// keep numbers exact, unix times don't fit in a float32 and ids shouldn't
+	// come back as 1e+06
+	decoder.UseNumber()
+
+	dump := &BackupDump{}
+	if err := decoder.Decode(dump); err != nil {
+		return nil, err
+	}
+	if dump.Format != backupFormat {
+		return nil, errors.New("not an ecc backup")
+	}
+	for table, data := range dump.Tables {
+		if !sqlName.MatchString(table) {
+			return nil, fmt.Errorf("bad table name %q", table)
+		}
+		for _, column := range data.Columns {
+			if !sqlName.MatchString(column) {
+				return nil, fmt.Errorf("bad column name %q in %s", column, table)
+			}
+		}
+		for _, row := range data.Rows {
+			if len(row) != len(data.Columns) {
+				return nil, fmt.Errorf("row in %s has %v values for %v columns", table, len(row), len(data.Columns))
+			}
+			for i, value := range row {
+				if number, ok := value.(json.Number); ok {
+					if n, err := number.Int64(); err == nil {
+						row[i] = n
+					} else if f, err := number.Float64(); err == nil {
+						row[i] = f
+					}
+				}
+			}
+		}
+	}
+	return dump, nil
+}
+
+func loadTable(tx *sql.Tx, table string, data *BackupData) error {
+	placeholders := ""
+	columns := ""
+	for i, column := range data.Columns {
+		if i > 0 {
+			placeholders += ", "
+			columns += ", "
+		}
+		placeholders += "?"
+		columns += column
+	}
+
+	stmt, err := tx.Prepare("insert into " + table + "(" + columns + ") values(" + placeholders + ")")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range data.Rows {
+		if _, err := stmt.Exec(row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreDump loads a dump into an empty database. The schema is built up
+// to the version the dump was made at so the columns line up, then the rest
+// of the migrations run on top like any other upgrade.
+func restoreDump(db *sql.DB, dump *BackupDump) (int, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return 0, err
+	}
+	if dump.SchemaVersion > len(migrations) {
+		return 0, fmt.Errorf("backup is at schema version %v but this ecc only knows up to %v", dump.SchemaVersion, len(migrations))
+	}
+	if version, err := schemaVersion(db); err != nil || version != 0 {
+		return version, errors.New("can only restore into an empty database")
+	}
+	// a database from before the migrations were kept is at version 0 but
+	// already has the codes table, which is all migration 1 makes
+	start := dump.SchemaVersion
+	if start == 0 && len(dump.Tables) > 0 {
+		start = 1
+	}
+	if _, err := applyMigrations(db, migrations[:start]); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	for table, data := range dump.Tables {
+		if err := loadTable(tx, table, data); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("unable to restore %s: %w", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return applyMigrations(db, migrations)
+}
+
+func isSnapshot(file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(f, header); err != nil && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return string(header) == sqliteHeader, nil
+}
+
+func copyFile(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func restoreDumpFile(db *sql.DB, src string) (int, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	dump, err := readDump(f)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read %s: %w", src, err)
+	}
+	return restoreDump(db, dump)
+}
+
+// restoreFile builds the restored database next to dbPath and only moves it
+// into place once it's complete, so a bad backup never leaves a half
+// restored ecc.db behind
+func restoreFile(dbPath string, src string) (int, error) {
+	snapshot, err := isSnapshot(src)
+	if err != nil {
+		return 0, err
+	}
+
+	tmpPath := dbPath + ".restore"
+	os.Remove(tmpPath)
+	defer os.Remove(tmpPath)
+
+	if snapshot {
+		if err := copyFile(src, tmpPath); err != nil {
+			return 0, err
+		}
+	}
+
+	db, err := sql.Open(sqliteDriver, tmpPath)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var version int
+	if snapshot {
+		var result string
+		if err := db.QueryRow("pragma integrity_check").Scan(&result); err != nil {
+			return 0, err
+		}
+		if result != "ok" {
+			return 0, fmt.Errorf("backup is damaged: %s", result)
+		}
+		// an older snapshot gets upgraded the same way an older ecc.db would
+		version, err = migrateDB(db)
+	} else {
+		version, err = restoreDumpFile(db, src)
+	}
+	if err != nil {
+		return version, err
+	}
+	if err := db.Close(); err != nil {
+		return version, err
+	}
+
+	return version, os.Rename(tmpPath, dbPath)
+}
+
+// runBackup is `ecc backup [-json] file`. It backs up the database as it
+// is, before any migrations run, so it's safe to take before upgrading.
+func runBackup(dbPath string, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "write a portable json dump instead of a sqlite snapshot")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ecc backup [-json] file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("backup needs a file to write to")
+	}
+	dest := flags.Arg(0)
+
+	// opening a sqlite file that isn't there would make an empty one
+	if info, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("nothing to back up: %w", err)
+	} else if info.Size() == 0 {
+		return fmt.Errorf("nothing to back up: %s is empty", dbPath)
+	}
+	db, err := sql.Open(sqliteDriver, dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if !*asJSON {
+		return backupSnapshot(db, dest)
+	}
+
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if err := dumpDB(db, f); err != nil {
+		f.Close()
+		os.Remove(dest)
+		return err
+	}
+	return f.Close()
+}
+
+// runRestore is `ecc restore [-force] file`. ecc shouldn't be running while
+// it restores, or it will keep writing to the old database.
+func runRestore(dbPath string, args []string) (int, error) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	force := flags.Bool("force", false, "replace the database even if it already has something in it")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ecc restore [-force] file")
+		fmt.Fprintln(flags.Output(), "file can be a snapshot or a json dump made with ecc backup")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 0, errors.New("restore needs a backup file to read")
+	}
+
+	if info, err := os.Stat(dbPath); err == nil && info.Size() > 0 && !*force {
+		return 0, fmt.Errorf("%s already exists, use -force to replace it", dbPath)
+	}
+	return restoreFile(dbPath, flags.Arg(0))
+}
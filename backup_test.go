@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDumpAndRestore(t *testing.T) {
+	testDB := setupTestDB(t)
+	setNickname(newSQLStore(testDB), "session1", "codebreaker")
+
+	var buf bytes.Buffer
+	if err := dumpDB(testDB, &buf); err != nil {
+		t.Fatalf("error in dumpDB(): %s", err)
+	}
+	if strings.Contains(buf.String(), "schema_version") {
+		t.Errorf("dumpDB() should leave the migration bookkeeping out")
+	}
+
+	dump, err := readDump(&buf)
+	if err != nil {
+		t.Fatalf("error in readDump(): %s", err)
+	}
+	if len(dump.Tables["codes"].Rows) != 1 {
+		t.Errorf("dumpDB() expected 1 code, got: %v", dump.Tables["codes"].Rows)
+	}
+
+	restored, err := sql.Open(sqliteDriver, ":memory:")
+	if err != nil {
+		t.Fatalf("unable to create testing db: %s", err)
+	}
+	restored.SetMaxOpenConns(1)
+	defer restored.Close()
+
+	version, err := restoreDump(restored, dump)
+	if err != nil {
+		t.Fatalf("error in restoreDump(): %s", err)
+	}
+	if version != dump.SchemaVersion {
+		t.Errorf("restoreDump() expected version %v, got: %v", dump.SchemaVersion, version)
+	}
+
+	store := newSQLStore(restored)
+	hash, err := store.GetSecretHash("testpath")
+	if err != nil || !comparePasswords(hash, "password123") {
+		t.Errorf("restoreDump() lost the secret for testpath: %v", err)
+	}
+	if versions, _ := store.History("testpath"); len(versions) != 1 || versions[0].CreatedAt == 0 {
+		t.Errorf("restoreDump() lost the history for testpath: %v", versions)
+	}
+	if streak, _ := getPlayerStreak(restored, "session1"); streak.Nickname != "codebreaker" {
+		t.Errorf("restoreDump() lost the nickname, got: %v", streak.Nickname)
+	}
+
+	// a database that already has data in it is left alone
+	if _, err := restoreDump(restored, dump); err == nil {
+		t.Errorf("restoreDump() expected an error restoring into a database with data")
+	}
+}
+
+func TestReadDumpErrors(t *testing.T) {
+	bad := []string{
+		`not json`,
+		`{"format": "something else"}`,
+		`{"format": "ecc-backup", "tables": {"codes; drop table codes": {"columns": [], "rows": []}}}`,
+		`{"format": "ecc-backup", "tables": {"codes": {"columns": ["path"], "rows": [["a", "b"]]}}}`,
+	}
+	for _, dump := range bad {
+		if _, err := readDump(strings.NewReader(dump)); err == nil {
+			t.Errorf("readDump() expected an error for %s", dump)
+		}
+	}
+}
+
+// setupBackupDB makes a database file to back up, since runBackup opens the
+// file itself
+func setupBackupDB(t *testing.T, dbPath string, schema string) {
+	db, err := sql.Open(sqliteDriver, dbPath)
+	if err != nil {
+		t.Fatalf("unable to create testing db: %s", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("unable to create testing db table structure: %s", err)
+	}
+	b, _ := json.Marshal(getDefaultCodeMap())
+	if _, err := db.Exec("insert into codes(path, password, valueMap) values(?, ?, ?)", "testpath", "hash", string(b)); err != nil {
+		t.Fatalf("unable to add testpath: %s", err)
+	}
+}
+
+func TestBackupAndRestoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src.db")
+	setupBackupDB(t, srcPath, "create table codes (path text not null primary key, password text, valueMap text)")
+	src, _ := sql.Open(sqliteDriver, srcPath)
+	if _, err := migrateDB(src); err != nil {
+		t.Fatalf("error in migrateDB(): %s", err)
+	}
+	src.Close()
+
+	snapshot := filepath.Join(dir, "snap.db")
+	if err := runBackup(srcPath, []string{snapshot}); err != nil {
+		t.Fatalf("error in runBackup(): %s", err)
+	}
+	if err := runBackup(srcPath, []string{snapshot}); err == nil {
+		t.Errorf("runBackup() expected an error for a file that's already there")
+	}
+	dumpFile := filepath.Join(dir, "dump.json")
+	if err := runBackup(srcPath, []string{"-json", dumpFile}); err != nil {
+		t.Fatalf("error in runBackup(): %s", err)
+	}
+
+	dbPath := filepath.Join(dir, "ecc.db")
+	for _, src := range []string{snapshot, dumpFile} {
+		if _, err := runRestore(dbPath, []string{"-force", src}); err != nil {
+			t.Fatalf("error in runRestore(%s): %s", src, err)
+		}
+
+		db, err := sql.Open(sqliteDriver, dbPath)
+		if err != nil {
+			t.Fatalf("unable to open restored db: %s", err)
+		}
+		if !isClaimed(newSQLStore(db), "testpath") {
+			t.Errorf("runRestore(%s) lost testpath", src)
+		}
+		db.Close()
+	}
+
+	if _, err := runRestore(dbPath, []string{dumpFile}); err == nil {
+		t.Errorf("runRestore() expected an error replacing a database without -force")
+	}
+
+	// a bad backup leaves the database as it was
+	junk := filepath.Join(dir, "junk.json")
+	os.WriteFile(junk, []byte("{"), 0644)
+	if _, err := runRestore(dbPath, []string{"-force", junk}); err == nil {
+		t.Errorf("runRestore() expected an error for a bad backup")
+	}
+	if info, err := os.Stat(dbPath); err != nil || info.Size() == 0 {
+		t.Errorf("runRestore() damaged the database after a bad backup: %v", err)
+	}
+	if _, err := os.Stat(dbPath + ".restore"); !os.IsNotExist(err) {
+		t.Errorf("runRestore() left its temporary file behind")
+	}
+}
+
+func TestBackupMissingDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "ecc.db")
+	if err := runBackup(dbPath, []string{"-json", filepath.Join(dir, "dump.json")}); err == nil {
+		t.Errorf("runBackup() expected an error for a database that isn't there")
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("runBackup() should not have made %s", dbPath)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dump.json")); !os.IsNotExist(err) {
+		t.Errorf("runBackup() should not have written a backup")
+	}
+}
+
+func TestRestoreOldDump(t *testing.T) {
+	// what -n used to make, backed up before upgrading so it's at version 0
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.db")
+	setupBackupDB(t, oldPath, "create table codes (path text not null primary key, password text, valueMap text)")
+
+	dumpFile := filepath.Join(dir, "dump.json")
+	if err := runBackup(oldPath, []string{"-json", dumpFile}); err != nil {
+		t.Fatalf("error in runBackup(): %s", err)
+	}
+	f, _ := os.Open(dumpFile)
+	dump, err := readDump(f)
+	f.Close()
+	if err != nil || dump.SchemaVersion != 0 {
+		t.Fatalf("runBackup() expected a version 0 dump, got: %v %v", dump, err)
+	}
+
+	dbPath := filepath.Join(dir, "ecc.db")
+	version, err := runRestore(dbPath, []string{dumpFile})
+	if err != nil {
+		t.Fatalf("error in runRestore(): %s", err)
+	}
+	migrations, _ := loadMigrations(migrationFiles)
+	if version != len(migrations) {
+		t.Errorf("runRestore() expected version %v, got: %v", len(migrations), version)
+	}
+
+	db, err := sql.Open(sqliteDriver, dbPath)
+	if err != nil {
+		t.Fatalf("unable to open restored db: %s", err)
+	}
+	defer db.Close()
+	store := newSQLStore(db)
+	if !isClaimed(store, "testpath") {
+		t.Errorf("runRestore() lost testpath")
+	}
+	if versions, err := store.History("testpath"); err != nil || len(versions) != 1 {
+		t.Errorf("runRestore() expected the old map as version 1, got: %v %v", versions, err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CaesarCrack is every possible caesar shift of some text, most english
+// looking first.
+type CaesarCrack struct {
+	Input  string
+	Shifts []ShiftGuess
+}
+
+// ShiftGuess is the text shifted back by Shift letters. The lower the
+// chi-squared score, the closer the letter counts are to english.
+type ShiftGuess struct {
+	Shift      int
+	ChiSquared float64
+	Score      string
+	Plaintext  string
+	Best       bool
+}
+
+// shiftCodeMap is a code map where every letter moves shift places along
+// the alphabet, wrapping around from z back to a
+func shiftCodeMap(shift int) map[string]string {
+	myMap := make(map[string]string)
+	for r := 'a'; r <= 'z'; r++ {
+		keyString := fmt.Sprintf("%c", r)
+		valueString := fmt.Sprintf("%c", 'a'+(r-'a'+rune(shift))%26)
+		myMap[keyString] = valueString
+	}
+
+	return myMap
+}
+
+// letterCounts counts each letter from a to z in text, in either case
+func letterCounts(text string) ([26]float64, float64) {
+	counts := [26]float64{}
+	total := 0.0
+	for _, char := range text {
+		switch {
+		case char >= 'a' && char <= 'z':
+			counts[char-'a']++
+		case char >= 'A' && char <= 'Z':
+			counts[char-'A']++
+		default:
+			continue
+		}
+		total++
+	}
+	return counts, total
+}
+
+// chiSquared measures how far the letter counts in text are from what
+// english would give for the same number of letters
+func chiSquared(text string) float64 {
+	counts, total := letterCounts(text)
+	return shiftedChiSquared(counts, total, 0)
+}
+
+// shiftedChiSquared is the chiSquared of text shifted back shift letters,
+// worked out from the counts so the text doesn't have to be shifted first
+func shiftedChiSquared(counts [26]float64, total float64, shift int) float64 {
+	if total == 0 {
+		return 0
+	}
+	score := 0.0
+	for letter := 0; letter < 26; letter++ {
+		expected := englishLetterFreq[string(rune('a'+letter))] * total / 100
+		diff := counts[(letter+shift)%26] - expected
+		score += diff * diff / expected
+	}
+	return score
+}
+
+// shiftBack moves every letter back shift places, keeping its case.
+// Everything else, like punctuation and digits, is left as it is.
+func shiftBack(input string, shift int) string {
+	var plaintext strings.Builder
+	plaintext.Grow(len(input))
+	for _, char := range input {
+		switch {
+		case char >= 'a' && char <= 'z':
+			char = 'a' + (char-'a'+26-rune(shift))%26
+		case char >= 'A' && char <= 'Z':
+			char = 'A' + (char-'A'+26-rune(shift))%26
+		}
+		plaintext.WriteRune(char)
+	}
+	return plaintext.String()
+}
+
+// crackCaesar ranks all 25 shifts by their letter counts, which only have
+// to be counted once, and shifts the text back for each of them
+func crackCaesar(input string) *CaesarCrack {
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+
+	counts, total := letterCounts(input)
+	crack := &CaesarCrack{Input: input}
+	for shift := 1; shift < 26; shift++ {
+		score := shiftedChiSquared(counts, total, shift)
+		crack.Shifts = append(crack.Shifts, ShiftGuess{
+			Shift:      shift,
+			ChiSquared: score,
+			Score:      fmt.Sprintf("%.1f", score),
+			Plaintext:  shiftBack(input, shift),
+		})
+	}
+
+	sort.SliceStable(crack.Shifts, func(i, j int) bool {
+		return crack.Shifts[i].ChiSquared < crack.Shifts[j].ChiSquared
+	})
+	crack.Shifts[0].Best = true
+	return crack
+}
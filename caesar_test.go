@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestShiftCodeMap(t *testing.T) {
+	myMap := shiftCodeMap(3)
+	if myMap["a"] != "d" || myMap["x"] != "a" || myMap["z"] != "c" {
+		t.Errorf("shiftCodeMap() expected a=d x=a z=c, got: %s %s %s", myMap["a"], myMap["x"], myMap["z"])
+	}
+}
+
+func TestChiSquared(t *testing.T) {
+	english := chiSquared("the quick brown fox jumps over the lazy dog and keeps on running")
+	nonsense := chiSquared("zzq xjv qqzk zkx vjqq zqx kzj")
+	if english >= nonsense {
+		t.Errorf("chiSquared() expected english to score lower, got: %v and %v", english, nonsense)
+	}
+}
+
+func TestShiftBack(t *testing.T) {
+	if plain := shiftBack("Dbc, 12 zA!", 1); plain != "Cab, 12 yZ!" {
+		t.Errorf("shiftBack() expected Cab, 12 yZ!, got: %s", plain)
+	}
+}
+
+func TestCrackCaesar(t *testing.T) {
+	encoded, _ := substitutionEncodeSteps(shiftCodeMap(7), "meet me by the big oak tree after school", false)
+
+	crack := crackCaesar(encoded)
+	if len(crack.Shifts) != 25 {
+		t.Errorf("crackCaesar() expected 25 shifts, got: %v", len(crack.Shifts))
+	}
+	best := crack.Shifts[0]
+	if !best.Best || best.Shift != 7 || best.Plaintext != "meet me by the big oak tree after school" {
+		t.Errorf("crackCaesar() expected shift 7 first, got: %v %s", best.Shift, best.Plaintext)
+	}
+
+	// capitals, punctuation and digits come through as they were
+	encoded = "Phhw ph eb wkh ELJ rdn wuhh diwhu vfkrro, dw 3:30!"
+	if best := crackCaesar(encoded).Shifts[0]; best.Shift != 3 || best.Plaintext != "Meet me by the BIG oak tree after school, at 3:30!" {
+		t.Errorf("crackCaesar() expected shift 3 with the punctuation kept, got: %v %s", best.Shift, best.Plaintext)
+	}
+
+	if crackCaesar("   ") != nil {
+		t.Errorf("crackCaesar() expected nothing back for blank input")
+	}
+}
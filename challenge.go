@@ -0,0 +1,287 @@
+package main
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+)
+
+//go:embed data/quotes.txt
+var quotesFile string
+
+const (
+	challengeSubstitution = "substitution"
+	challengeCaesar       = "caesar"
+	challengeAtbash       = "atbash"
+)
+
+var challengeCiphers = []string{challengeSubstitution, challengeCaesar, challengeAtbash}
+
+// Quote is one line of data/quotes.txt, "text|author".
+type Quote struct {
+	Text   string
+	Author string
+}
+
+var quotes = loadQuotes(quotesFile)
+
+// Challenge is a quote encoded with a random cipher for people to crack.
+type Challenge struct {
+	ID         string
+	Quote      string
+	Author     string
+	CipherType string
+	ValueMap   map[string]string
+	Ciphertext string
+	CreatedAt  int64
+}
+
+// ChallengePage is what a player sees of a challenge. The quote is only
+// filled in once they have solved it.
+type ChallengePage struct {
+	ID         string
+	Ciphertext string
+	Attempts   int
+	Solved     bool
+	SolveTime  string
+	Guess      string
+	WrongGuess bool
+	Quote      string
+	Author     string
+	Players    int
+	Solvers    int
+	Fastest    string
+	Daily      *DailyInfo
+	Hints      *ChallengeHints
+	Score      int
+	Difficulty *Difficulty
+}
+
+type challengePlayer struct {
+	StartedAt int64
+	SolvedAt  int64
+	Attempts  int
+}
+
+func loadQuotes(file string) []Quote {
+	loaded := []Quote{}
+	for _, line := range strings.Split(file, "\n") {
+		text, author, _ := strings.Cut(strings.TrimSpace(line), "|")
+		if text != "" {
+			loaded = append(loaded, Quote{Text: text, Author: author})
+		}
+	}
+	return loaded
+}
+
+// randomCodeMap mixes up the alphabet so that no letter stands for itself,
+// which is the usual rule for newspaper cryptograms
+func randomCodeMap(rng *rand.Rand) map[string]string {
+	for {
+		perm := rng.Perm(26)
+		fixed := false
+		for i, p := range perm {
+			if i == p {
+				fixed = true
+				break
+			}
+		}
+		if fixed {
+			continue
+		}
+
+		myMap := make(map[string]string)
+		for i, p := range perm {
+			myMap[string(rune('a'+i))] = string(rune('a' + p))
+		}
+		return myMap
+	}
+}
+
+// cryptogramEncode is like substitutionEncode but keeps punctuation, which
+// gives solvers a few clues the way printed cryptograms do
+func cryptogramEncode(myMap map[string]string, toEncode string) string {
+	valToReturn := ""
+	for _, char := range strings.ToLower(toEncode) {
+		if v, ok := myMap[string(char)]; ok {
+			valToReturn += v
+		} else {
+			valToReturn += string(char)
+		}
+	}
+	return valToReturn
+}
+
+func generateChallenge(rng *rand.Rand) *Challenge {
+	quote := quotes[rng.Intn(len(quotes))]
+	cipherType := challengeCiphers[rng.Intn(len(challengeCiphers))]
+
+	var myMap map[string]string
+	switch cipherType {
+	case challengeCaesar:
+		myMap = shiftCodeMap(1 + rng.Intn(25))
+	case challengeAtbash:
+		myMap = getDefaultCodeMap()
+	default:
+		myMap = randomCodeMap(rng)
+	}
+
+	return &Challenge{
+		Quote:      quote.Text,
+		Author:     quote.Author,
+		CipherType: cipherType,
+		ValueMap:   myMap,
+		Ciphertext: cryptogramEncode(myMap, quote.Text),
+	}
+}
+
+// lettersOnly is what gets compared when checking an answer, so nobody
+// gets marked wrong over a missing comma or capital letter
+func lettersOnly(input string) string {
+	valToReturn := ""
+	for _, char := range strings.ToLower(input) {
+		if unicode.IsLetter(char) {
+			valToReturn += string(char)
+		}
+	}
+	return valToReturn
+}
+
+func checkSolution(challenge *Challenge, guess string) bool {
+	return lettersOnly(guess) != "" && lettersOnly(guess) == lettersOnly(challenge.Quote)
+}
+
+func formatDuration(seconds int64) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+func createChallenge(db *sql.DB, challenge *Challenge) error {
+	b, err := json.Marshal(challenge.ValueMap)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("insert into challenges(id, quote, author, cipher, valueMap, ciphertext, created_at) values(?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(challenge.ID, challenge.Quote, challenge.Author, challenge.CipherType, string(b), challenge.Ciphertext, challenge.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func getChallengeRecord(db *sql.DB, id string) (*Challenge, error) {
+	stmt, err := db.Prepare("select id, quote, author, cipher, valueMap, ciphertext, created_at from challenges where id = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	challenge := &Challenge{}
+	var valueMapDB string
+	err = stmt.QueryRow(id).Scan(&challenge.ID, &challenge.Quote, &challenge.Author, &challenge.CipherType, &valueMapDB, &challenge.Ciphertext, &challenge.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal([]byte(valueMapDB), &challenge.ValueMap); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// startChallengePlayer starts the clock for a player the first time they
+// open a challenge, and returns how they are doing so far
+func startChallengePlayer(db *sql.DB, id string, session string, now int64) (*challengePlayer, error) {
+	_, err := db.Exec(`insert into challenge_players(challenge_id, session, started_at, solved_at, attempts) values(?, ?, ?, 0, 0)
+		on conflict(challenge_id, session) do nothing`, id, session, now)
+	if err != nil {
+		return nil, err
+	}
+
+	player := &challengePlayer{}
+	err = db.QueryRow("select started_at, solved_at, attempts from challenge_players where challenge_id = ? and session = ?", id, session).
+		Scan(&player.StartedAt, &player.SolvedAt, &player.Attempts)
+	if err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// recordChallengeAttempt counts a guess, and stops the clock the first time
+// a player gets it right
+func recordChallengeAttempt(db *sql.DB, id string, session string, correct bool, now int64) error {
+	solvedAt := int64(0)
+	if correct {
+		solvedAt = now
+	}
+	_, err := db.Exec(`update challenge_players set attempts = attempts + 1,
+		solved_at = case when solved_at = 0 then ? else solved_at end
+		where challenge_id = ? and session = ?`, solvedAt, id, session)
+	return err
+}
+
+// getChallengeStats returns how many people have tried a challenge, how many
+// solved it and the fastest solve in seconds
+func getChallengeStats(db *sql.DB, id string) (int, int, int64, error) {
+	var players, solvers int
+	var fastest sql.NullInt64
+	err := db.QueryRow(`select count(*), coalesce(sum(case when solved_at > 0 then 1 else 0 end), 0),
+		min(case when solved_at > 0 then solved_at - started_at end)
+		from challenge_players where challenge_id = ?`, id).Scan(&players, &solvers, &fastest)
+	return players, solvers, fastest.Int64, err
+}
+
+// challengePage puts together what the player should see
+func challengePage(players PlayerStore, challenge *Challenge, session string, player *challengePlayer) *ChallengePage {
+	page := &ChallengePage{
+		ID:         challenge.ID,
+		Ciphertext: challenge.Ciphertext,
+		Attempts:   player.Attempts,
+		Solved:     player.SolvedAt > 0,
+		Difficulty: rateDifficulty(challenge),
+	}
+	hints, err := players.Hints(challenge.ID, session)
+	if err != nil {
+		log.Println("unable to load hints: ", err)
+	}
+	page.Hints = buildHints(challenge, hints)
+
+	if page.Solved {
+		page.Score = challengeScore(player.Attempts, hints)
+		page.SolveTime = formatDuration(player.SolvedAt - player.StartedAt)
+		page.Quote = challenge.Quote
+		page.Author = challenge.Author
+	}
+
+	if day, ok := dailyDay(challenge.ID); ok {
+		page.Daily = dailyInfo(players, day, session)
+	}
+
+	tried, solvers, fastest, err := players.ChallengeStats(challenge.ID)
+	if err != nil {
+		return page
+	}
+	page.Players = tried
+	page.Solvers = solvers
+	if solvers > 0 {
+		page.Fastest = formatDuration(fastest)
+	}
+	return page
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLoadQuotes(t *testing.T) {
+	loaded := loadQuotes("Slow and steady wins the race.|Aesop\n\n  Look before you leap.|Proverb  \n")
+	if len(loaded) != 2 {
+		t.Fatalf("loadQuotes() expected 2 quotes, got: %v", len(loaded))
+	}
+	if loaded[1].Text != "Look before you leap." || loaded[1].Author != "Proverb" {
+		t.Errorf("loadQuotes() expected Look before you leap. by Proverb, got: %v", loaded[1])
+	}
+
+	if len(quotes) == 0 {
+		t.Errorf("expected the bundled quotes to load")
+	}
+}
+
+func TestRandomCodeMap(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		myMap := randomCodeMap(rng)
+		seen := make(map[string]bool)
+		for k, v := range myMap {
+			if k == v {
+				t.Errorf("randomCodeMap() should not map %s to itself", k)
+			}
+			seen[v] = true
+		}
+		if len(seen) != 26 {
+			t.Errorf("randomCodeMap() expected 26 different letters, got: %v", len(seen))
+		}
+	}
+}
+
+func TestGenerateChallenge(t *testing.T) {
+	first := generateChallenge(rand.New(rand.NewSource(42)))
+	second := generateChallenge(rand.New(rand.NewSource(42)))
+	if first.Ciphertext != second.Ciphertext {
+		t.Errorf("generateChallenge() expected the same seed to give the same challenge")
+	}
+
+	decoded, _ := substitutionDecodeSteps(first.ValueMap, lettersOnly(first.Ciphertext), false)
+	if decoded != lettersOnly(first.Quote) {
+		t.Errorf("generateChallenge() ciphertext does not decode to the quote: %s", decoded)
+	}
+
+	if !checkSolution(first, first.Quote+"!!") {
+		t.Errorf("checkSolution() should ignore punctuation")
+	}
+	if checkSolution(first, "") || checkSolution(first, "nope") {
+		t.Errorf("checkSolution() should not accept a wrong answer")
+	}
+}
+
+func TestCryptogramEncode(t *testing.T) {
+	encoded := cryptogramEncode(getDefaultCodeMap(), "I came, I saw!")
+	if encoded != "r xznv, r hzd!" {
+		t.Errorf("cryptogramEncode() expected r xznv, r hzd!, got: %s", encoded)
+	}
+}
+
+func TestChallengeRecords(t *testing.T) {
+	testDB := setupTestDB(t)
+
+	challenge := generateChallenge(rand.New(rand.NewSource(7)))
+	challenge.ID = "abc123"
+	challenge.CreatedAt = 1000
+	if err := createChallenge(testDB, challenge); err != nil {
+		t.Fatalf("error in createChallenge(): %s", err)
+	}
+
+	loaded, err := getChallengeRecord(testDB, "abc123")
+	if err != nil {
+		t.Fatalf("error in getChallengeRecord(): %s", err)
+	}
+	if loaded.Ciphertext != challenge.Ciphertext || loaded.ValueMap["a"] != challenge.ValueMap["a"] {
+		t.Errorf("getChallengeRecord() did not load the challenge that was saved")
+	}
+
+	if _, err := getChallengeRecord(testDB, "nope"); err == nil {
+		t.Errorf("getChallengeRecord() expected an error for a missing challenge")
+	}
+
+	// the clock starts the first time, opening it again doesn't reset it
+	startChallengePlayer(testDB, "abc123", "player1", 2000)
+	player, err := startChallengePlayer(testDB, "abc123", "player1", 2500)
+	if err != nil {
+		t.Fatalf("error in startChallengePlayer(): %s", err)
+	}
+	if player.StartedAt != 2000 {
+		t.Errorf("startChallengePlayer() expected to start at 2000, got: %v", player.StartedAt)
+	}
+
+	recordChallengeAttempt(testDB, "abc123", "player1", false, 2010)
+	recordChallengeAttempt(testDB, "abc123", "player1", true, 2090)
+	recordChallengeAttempt(testDB, "abc123", "player1", true, 3000)
+	startChallengePlayer(testDB, "abc123", "player2", 2000)
+
+	player, _ = startChallengePlayer(testDB, "abc123", "player1", 4000)
+	if player.Attempts != 3 || player.SolvedAt != 2090 {
+		t.Errorf("recordChallengeAttempt() expected 3 attempts solved at 2090, got: %v", player)
+	}
+
+	players, solvers, fastest, err := getChallengeStats(testDB, "abc123")
+	if err != nil {
+		t.Fatalf("error in getChallengeStats(): %s", err)
+	}
+	if players != 2 || solvers != 1 || fastest != 90 {
+		t.Errorf("getChallengeStats() expected 2 players, 1 solver in 90s, got: %v %v %v", players, solvers, fastest)
+	}
+}
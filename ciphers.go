@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	cipherSubstitution = "substitution"
+	cipherScytale      = "scytale"
+	cipherRoute        = "route"
+	cipherNihilist     = "nihilist"
+	cipherCheckerboard = "checkerboard"
+	cipherVigenere     = "vigenere"
+
+	routeSpiral = "spiral"
+	routeSnake  = "snake"
+
+	defaultGridSize = 3
+)
+
+// CipherOptions are the extra settings the encode/decode forms can send
+// along with the text. An empty Type means the page's substitution map.
+type CipherOptions struct {
+	Type    string
+	Size    int    // rod diameter for scytale, number of columns for route
+	Route   string // spiral or snake
+	Key     string // key word for nihilist, checkerboard and vigenere
+	Explain bool   // show every step along with the answer
+}
+
+// CipherResult is what running a cipher over some text gives back.
+type CipherResult struct {
+	Text  string
+	Grid  *CipherGrid
+	Steps []ExplainStep
+}
+
+// ExplainStep is what happened to one character of the input on its way
+// through a cipher.
+type ExplainStep struct {
+	Input  string
+	Rule   string
+	Output string
+}
+
+func getCipherOptions(r *http.Request) (CipherOptions, error) {
+	opts := CipherOptions{
+		Type:    strings.TrimSpace(r.FormValue("cipherType")),
+		Size:    defaultGridSize,
+		Route:   strings.TrimSpace(r.FormValue("routeType")),
+		Key:     strings.TrimSpace(r.FormValue("cipherKey")),
+		Explain: r.FormValue("explain") != "",
+	}
+
+	if opts.Type == "" {
+		opts.Type = cipherSubstitution
+	}
+	if opts.Route == "" {
+		opts.Route = routeSpiral
+	}
+
+	switch opts.Type {
+	case cipherSubstitution:
+	case cipherScytale, cipherRoute:
+		if size := strings.TrimSpace(r.FormValue("gridSize")); size != "" {
+			n, err := strconv.Atoi(size)
+			if err != nil || n < 1 {
+				return opts, errors.New("Grid size must be a number bigger than 0")
+			}
+			opts.Size = n
+		}
+		if opts.Route != routeSpiral && opts.Route != routeSnake {
+			return opts, errors.New("Unknown route type")
+		}
+	case cipherNihilist:
+		if len(polybiusLetters(opts.Key)) == 0 {
+			return opts, errors.New("The nihilist cipher needs a key word")
+		}
+	case cipherVigenere:
+		if vigenereKeyLetters(opts.Key) == nil {
+			return opts, errors.New("The vigenere cipher needs a key word")
+		}
+	case cipherCheckerboard:
+	default:
+		return opts, errors.New("Unknown cipher type")
+	}
+
+	return opts, nil
+}
+
+func encodeText(myMap map[string]string, opts CipherOptions, toEncode string) CipherResult {
+	switch opts.Type {
+	case cipherScytale, cipherRoute:
+		text, grid, steps := transposeSteps(opts, toEncode, false, opts.Explain)
+		return CipherResult{Text: text, Grid: grid, Steps: steps}
+	case cipherNihilist:
+		text, steps := nihilistEncodeSteps(opts.Key, toEncode, opts.Explain)
+		return CipherResult{Text: text, Steps: steps}
+	case cipherCheckerboard:
+		text, steps := checkerboardEncodeSteps(opts.Key, toEncode, opts.Explain)
+		return CipherResult{Text: text, Steps: steps}
+	case cipherVigenere:
+		text, steps := vigenereShiftSteps(opts.Key, toEncode, false, opts.Explain)
+		return CipherResult{Text: text, Steps: steps}
+	default:
+		text, steps := substitutionEncodeSteps(myMap, toEncode, opts.Explain)
+		return CipherResult{Text: text, Steps: steps}
+	}
+}
+
+func decodeText(myMap map[string]string, opts CipherOptions, toDecode string) CipherResult {
+	switch opts.Type {
+	case cipherScytale, cipherRoute:
+		text, grid, steps := transposeSteps(opts, toDecode, true, opts.Explain)
+		return CipherResult{Text: text, Grid: grid, Steps: steps}
+	case cipherNihilist:
+		text, steps := nihilistDecodeSteps(opts.Key, toDecode, opts.Explain)
+		return CipherResult{Text: text, Steps: steps}
+	case cipherCheckerboard:
+		text, steps := checkerboardDecodeSteps(opts.Key, toDecode, opts.Explain)
+		return CipherResult{Text: text, Steps: steps}
+	case cipherVigenere:
+		text, steps := vigenereShiftSteps(opts.Key, toDecode, true, opts.Explain)
+		return CipherResult{Text: text, Steps: steps}
+	default:
+		text, steps := substitutionDecodeSteps(myMap, toDecode, opts.Explain)
+		return CipherResult{Text: text, Steps: steps}
+	}
+}
+
+// substitutionEncodeSteps encodes with the cipher table. The steps for the
+// explain view are only built when explain is set.
+func substitutionEncodeSteps(myMap map[string]string, toEncode string, explain bool) (string, []ExplainStep) {
+	var valToReturn strings.Builder
+	steps := []ExplainStep{}
+	for _, char := range toEncode {
+		if string(char) == " " {
+			valToReturn.WriteString(" ")
+			if explain {
+				steps = append(steps, ExplainStep{" ", "spaces stay the same", " "})
+			}
+		} else if v, ok := myMap[string(char)]; ok {
+			valToReturn.WriteString(v)
+			if explain {
+				steps = append(steps, ExplainStep{string(char), string(char) + " has " + v + " under it in the cipher table", v})
+			}
+		} else if explain {
+			steps = append(steps, ExplainStep{string(char), "not in the cipher table, left out", ""})
+		}
+	}
+	return valToReturn.String(), steps
+}
+
+func substitutionDecodeSteps(myMap map[string]string, toDecode string, explain bool) (string, []ExplainStep) {
+	// go through the letters in order so a symbol used for more than one
+	// letter always decodes the same way
+	keys := make([]string, 0, len(myMap))
+	for k := range myMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var valToReturn strings.Builder
+	steps := []ExplainStep{}
+	for _, char := range toDecode {
+		if string(char) == " " {
+			valToReturn.WriteString(" ")
+			if explain {
+				steps = append(steps, ExplainStep{" ", "spaces stay the same", " "})
+			}
+			continue
+		}
+
+		found := []string{}
+		for _, k := range keys {
+			if myMap[k] == string(char) {
+				found = append(found, k)
+			}
+		}
+		if len(found) == 0 {
+			if explain {
+				steps = append(steps, ExplainStep{string(char), "not in the cipher table, left out", ""})
+			}
+			continue
+		}
+		valToReturn.WriteString(strings.Join(found, ""))
+		if explain {
+			steps = append(steps, ExplainStep{string(char), string(char) + " is under " + strings.Join(found, " and ") + " in the cipher table", strings.Join(found, "")})
+		}
+	}
+	return valToReturn.String(), steps
+}
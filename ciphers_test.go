@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodeTextSteps(t *testing.T) {
+	myMap := getDefaultCodeMap()
+	delete(myMap, "c")
+
+	result := encodeText(myMap, CipherOptions{Type: cipherSubstitution, Explain: true}, "ab c")
+	if result.Text != "zy " {
+		t.Errorf("encodeText() expected \"zy \", got: %q", result.Text)
+	}
+	if len(result.Steps) != 4 {
+		t.Fatalf("encodeText() expected 4 steps, got: %v", len(result.Steps))
+	}
+	if result.Steps[0].Output != "z" || !strings.Contains(result.Steps[0].Rule, "cipher table") {
+		t.Errorf("encodeText() expected a map lookup for a, got: %v", result.Steps[0])
+	}
+	if result.Steps[2].Output != " " || result.Steps[3].Output != "" {
+		t.Errorf("encodeText() expected a space to pass and c to be left out, got: %v %v", result.Steps[2], result.Steps[3])
+	}
+
+	result = encodeText(myMap, CipherOptions{Type: cipherSubstitution}, "ab c")
+	if result.Text != "zy " || len(result.Steps) != 0 {
+		t.Errorf("encodeText() expected no steps when explain is off, got: %q %v", result.Text, result.Steps)
+	}
+}
+
+func TestDecodeTextSteps(t *testing.T) {
+	tests := []struct {
+		opts     CipherOptions
+		input    string
+		expected string
+		rule     string
+	}{
+		{CipherOptions{Type: cipherSubstitution, Explain: true}, "zy", "ab", "z is under a in the cipher table"},
+		{CipherOptions{Type: cipherVigenere, Key: "lemon", Explain: true}, "lxf", "att", "key letter l, move back 11"},
+		{CipherOptions{Type: cipherNihilist, Key: "key", Explain: true}, "55 53 26", "spy", "55 minus key letter k (11) is 44, which is s in the square"},
+		{CipherOptions{Type: cipherCheckerboard, Explain: true}, "31", "at", "a is in the top row, column 3"},
+		{CipherOptions{Type: cipherScytale, Size: 3, Explain: true}, "wdveieasrrceeod", "wearediscovered", "1st letter in, goes in row 1, column 1"},
+	}
+
+	for _, test := range tests {
+		result := decodeText(getDefaultCodeMap(), test.opts, test.input)
+		if result.Text != test.expected {
+			t.Errorf("decodeText() %s expected %s, got: %s", test.opts.Type, test.expected, result.Text)
+		}
+		if len(result.Steps) == 0 || result.Steps[0].Rule != test.rule {
+			t.Errorf("decodeText() %s expected the rule %q, got: %v", test.opts.Type, test.rule, result.Steps)
+		}
+	}
+}
+
+func TestTransposeSteps(t *testing.T) {
+	_, _, steps := transposeSteps(CipherOptions{Type: cipherScytale, Size: 3}, "we are discovered", false, true)
+	// steps follow the plaintext, e is written second but read out 4th
+	if steps[1].Input != "e" || steps[1].Rule != "written in row 1, column 2, read out 4th" {
+		t.Errorf("transposeSteps() expected e read out 4th, got: %v", steps[1])
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// CribPosition is one place a crib could sit in the ciphertext without
+// contradicting itself or the guesses made so far. Start counts symbols
+// from 1, ignoring spaces and punctuation.
+type CribPosition struct {
+	Start   int
+	Cipher  string
+	Mapping map[string]string
+	New     int
+}
+
+// CribReport is the result of sliding a crib along the ciphertext. Applied
+// is the position that was filled in to the guesses, if one was.
+type CribReport struct {
+	Crib      string
+	Checked   int
+	Positions []CribPosition
+	Applied   *CribPosition
+}
+
+func cribLetters(crib string) []rune {
+	letters := []rune{}
+	for _, char := range strings.ToLower(crib) {
+		if unicode.IsLetter(char) {
+			letters = append(letters, char)
+		}
+	}
+	return letters
+}
+
+func cipherSymbols(ciphertext string) []rune {
+	symbols := []rune{}
+	for _, char := range ciphertext {
+		if isCipherSymbol(char) {
+			symbols = append(symbols, char)
+		}
+	}
+	return symbols
+}
+
+// cribMapping lines a crib up against some ciphertext. In a monoalphabetic
+// cipher a symbol always stands for the same letter and no two symbols
+// stand for the same letter, so any clash rules the position out.
+func cribMapping(cipher []rune, crib []rune, guesses map[string]string) (map[string]string, bool) {
+	mapping := make(map[string]string)
+	usedBy := make(map[string]string)
+	for symbol, plain := range guesses {
+		if plain != "" {
+			usedBy[plain] = symbol
+		}
+	}
+
+	for i, char := range cipher {
+		symbol, plain := string(char), string(crib[i])
+		if guess := guesses[symbol]; guess != "" && guess != plain {
+			return nil, false
+		}
+		if m, ok := mapping[symbol]; ok && m != plain {
+			return nil, false
+		}
+		if s, ok := usedBy[plain]; ok && s != symbol {
+			return nil, false
+		}
+		mapping[symbol] = plain
+		usedBy[plain] = symbol
+	}
+	return mapping, true
+}
+
+// slideCrib tries the crib at every position in the ciphertext. Spaces are
+// ignored on both sides, the way cribs were used against enigma messages
+// that had no word breaks.
+func slideCrib(workspace *Workspace, crib string) (*CribReport, error) {
+	letters := cribLetters(crib)
+	if len(letters) == 0 {
+		return nil, errors.New("Type in a word you think is in the message")
+	}
+	symbols := cipherSymbols(workspace.Ciphertext)
+	if len(letters) > len(symbols) {
+		return nil, errors.New("The crib is longer than the secret message")
+	}
+
+	report := &CribReport{Crib: string(letters), Positions: []CribPosition{}}
+	for start := 0; start+len(letters) <= len(symbols); start++ {
+		report.Checked++
+		window := symbols[start : start+len(letters)]
+		mapping, ok := cribMapping(window, letters, workspace.Guesses)
+		if !ok {
+			continue
+		}
+
+		position := CribPosition{Start: start + 1, Cipher: string(window), Mapping: mapping}
+		for symbol := range mapping {
+			if workspace.Guesses[symbol] == "" {
+				position.New++
+			}
+		}
+		report.Positions = append(report.Positions, position)
+	}
+	return report, nil
+}
+
+// position finds the crib position starting at start, if it still fits
+func (report *CribReport) position(start int) (*CribPosition, bool) {
+	for i := range report.Positions {
+		if report.Positions[i].Start == start {
+			return &report.Positions[i], true
+		}
+	}
+	return nil, false
+}
+
+// applyCrib fills in the guesses a crib position gives
+func (workspace *Workspace) applyCrib(position *CribPosition) {
+	for symbol, plain := range position.Mapping {
+		workspace.guess(symbol, plain)
+	}
+}
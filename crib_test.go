@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCribMapping(t *testing.T) {
+	mapping, ok := cribMapping([]rune("svool"), []rune("hello"), nil)
+	if !ok || mapping["s"] != "h" || mapping["o"] != "l" || len(mapping) != 4 {
+		t.Errorf("cribMapping() expected svool to fit hello, got: %v", mapping)
+	}
+
+	// the same symbol can't be two different letters
+	if _, ok := cribMapping([]rune("svoos"), []rune("hello"), nil); ok {
+		t.Errorf("cribMapping() expected svoos not to fit hello")
+	}
+
+	// two symbols can't be the same letter
+	if _, ok := cribMapping([]rune("svoxl"), []rune("hello"), nil); ok {
+		t.Errorf("cribMapping() expected svoxl not to fit hello")
+	}
+
+	if _, ok := cribMapping([]rune("svool"), []rune("hello"), map[string]string{"s": "j"}); ok {
+		t.Errorf("cribMapping() expected a clash with the guess for s")
+	}
+	if _, ok := cribMapping([]rune("svool"), []rune("hello"), map[string]string{"a": "h"}); ok {
+		t.Errorf("cribMapping() expected a clash with h already being used for a")
+	}
+}
+
+func TestSlideCrib(t *testing.T) {
+	// "attack at dawn" with the alphabet backwards
+	encoded, _ := substitutionEncodeSteps(getDefaultCodeMap(), "attack at dawn", false)
+	workspace := newWorkspace(encoded, nil)
+
+	report, err := slideCrib(workspace, "Dawn")
+	if err != nil {
+		t.Fatalf("error in slideCrib(): %s", err)
+	}
+	if report.Checked != 9 {
+		t.Errorf("slideCrib() expected to try 9 places, got: %v", report.Checked)
+	}
+	position, ok := report.position(9)
+	if !ok || position.Cipher != "wzdm" || position.New != 4 {
+		t.Errorf("slideCrib() expected dawn to fit at 9 under wzdm, got: %v", report.Positions)
+	}
+	for _, pos := range report.Positions {
+		if pos.Start == 2 {
+			t.Errorf("slideCrib() should not fit dawn over ggzx, the g can't be both d and a")
+		}
+	}
+
+	workspace.applyCrib(position)
+	workspace.render()
+	if workspace.Guesses["w"] != "d" || workspace.Guesses["m"] != "n" || workspace.Solved != 4 {
+		t.Errorf("applyCrib() expected w=d and m=n, got: %v", workspace.Guesses)
+	}
+
+	if _, err := slideCrib(workspace, "123"); err == nil {
+		t.Errorf("slideCrib() expected an error for a crib with no letters")
+	}
+	if _, err := slideCrib(workspace, "attackatdawnplease"); err == nil {
+		t.Errorf("slideCrib() expected an error for a crib longer than the message")
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	dailyPrefix     = "daily-"
+	dayFormat       = "2006-01-02"
+	leaderboardSize = 10
+)
+
+// PlayerStreak is how many days in a row somebody has solved the daily
+// puzzle. Nickname is empty until they pick one.
+type PlayerStreak struct {
+	Nickname  string
+	Streak    int
+	Best      int
+	LastDaily string
+}
+
+// LeaderboardEntry is one line of a daily puzzle's leaderboard. Only the
+// nickname is ever shown, never the session.
+type LeaderboardEntry struct {
+	Rank     int
+	Nickname string
+	Time     string
+	Attempts int
+	Score    int
+	Streak   int
+}
+
+// DailyInfo is the extra bit of a challenge page for the daily puzzle.
+type DailyInfo struct {
+	Day         string
+	Today       bool
+	Nickname    string
+	Streak      int
+	Best        int
+	Leaderboard []LeaderboardEntry
+}
+
+// everybody gets the same puzzle on the same day because the random
+// numbers are seeded from the date
+func dailySeed(day time.Time) int64 {
+	y, m, d := day.Date()
+	return int64(y*10000 + int(m)*100 + d)
+}
+
+func dailyChallenge(day time.Time) *Challenge {
+	challenge := generateChallenge(rand.New(rand.NewSource(dailySeed(day))))
+	challenge.ID = dailyPrefix + day.Format(dayFormat)
+	challenge.CreatedAt = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location()).Unix()
+	return challenge
+}
+
+// dailyDay returns the day a daily challenge is for, or false if the id
+// isn't a daily challenge
+func dailyDay(id string) (string, bool) {
+	day, ok := strings.CutPrefix(id, dailyPrefix)
+	if !ok {
+		return "", false
+	}
+	if _, err := time.Parse(dayFormat, day); err != nil {
+		return "", false
+	}
+	return day, true
+}
+
+func previousDay(day string) string {
+	t, err := time.Parse(dayFormat, day)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, -1).Format(dayFormat)
+}
+
+// getDailyChallenge saves the day's challenge the first time somebody asks
+// for it. Two people asking at once is fine, they make the same puzzle.
+func getDailyChallenge(players PlayerStore, day time.Time) (*Challenge, error) {
+	challenge := dailyChallenge(day)
+	saved, err := players.GetChallenge(challenge.ID)
+	if err == nil {
+		return saved, nil
+	}
+	if err != ErrChallengeNotFound {
+		return nil, err
+	}
+
+	if err := players.CreateChallenge(challenge); err != nil {
+		if saved, err := players.GetChallenge(challenge.ID); err == nil {
+			return saved, nil
+		}
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// validNickname keeps leaderboard names to nicknames: one short word with
+// no spaces, so nobody ends up putting their full name up there
+func validNickname(nickname string) error {
+	if len(nickname) < 3 || len(nickname) > 16 {
+		return errors.New("Nicknames must be between 3 and 16 characters")
+	}
+
+	hasLetter := false
+	for _, char := range nickname {
+		switch {
+		case unicode.IsSpace(char):
+			return errors.New("Nicknames can't have spaces, pick a nickname instead of your real name")
+		case char > unicode.MaxASCII:
+			return errors.New("Nicknames can only use letters, numbers, - and _")
+		case unicode.IsLetter(char):
+			hasLetter = true
+		case unicode.IsDigit(char) || char == '-' || char == '_':
+		default:
+			return errors.New("Nicknames can only use letters, numbers, - and _")
+		}
+	}
+	if !hasLetter {
+		return errors.New("Nicknames need at least one letter")
+	}
+	return nil
+}
+
+func getPlayerStreak(db *sql.DB, session string) (*PlayerStreak, error) {
+	player := &PlayerStreak{}
+	var nickname sql.NullString
+	err := db.QueryRow("select nickname, streak, best_streak, last_daily from players where session = ?", session).
+		Scan(&nickname, &player.Streak, &player.Best, &player.LastDaily)
+	if err == sql.ErrNoRows {
+		return player, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	player.Nickname = nickname.String
+	return player, nil
+}
+
+// setNickname picks the name a player shows up as on the leaderboard.
+// Nicknames are unique so two kids can't both be "ninja".
+func setNickname(players PlayerStore, session string, nickname string) error {
+	if err := validNickname(nickname); err != nil {
+		return err
+	}
+	return players.SetNickname(session, nickname)
+}
+
+func setPlayerNickname(db *sql.DB, session string, nickname string) error {
+	var owner string
+	err := db.QueryRow("select session from players where lower(nickname) = lower(?)", nickname).Scan(&owner)
+	if err == nil && owner != session {
+		return ErrNicknameTaken
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	_, err = db.Exec(`insert into players(session, nickname, streak, best_streak, last_daily) values(?, ?, 0, 0, '')
+		on conflict(session) do update set nickname = excluded.nickname`, session, nickname)
+	return err
+}
+
+// recordDailySolve puts a solve on the day's leaderboard and keeps the
+// player's streak going, or starts a new one if they missed a day
+func recordDailySolve(players PlayerStore, session string, day string, seconds int64, attempts int, score int) (*PlayerStreak, error) {
+	player, err := players.GetPlayer(session)
+	if err != nil {
+		return nil, err
+	}
+	if player.LastDaily == day {
+		return player, nil
+	}
+
+	if player.LastDaily != "" && player.LastDaily == previousDay(day) {
+		player.Streak++
+	} else {
+		player.Streak = 1
+	}
+	if player.Streak > player.Best {
+		player.Best = player.Streak
+	}
+	player.LastDaily = day
+
+	return player, players.SaveDailySolve(player, session, day, seconds, attempts, score)
+}
+
+func saveDailySolve(db *sql.DB, player *PlayerStreak, session string, day string, seconds int64, attempts int, score int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`insert into players(session, streak, best_streak, last_daily) values(?, ?, ?, ?)
+		on conflict(session) do update set streak = excluded.streak, best_streak = excluded.best_streak, last_daily = excluded.last_daily`,
+		session, player.Streak, player.Best, player.LastDaily)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(`insert into daily_scores(day, session, seconds, attempts, score) values(?, ?, ?, ?, ?)
+		on conflict(day, session) do nothing`, day, session, seconds, attempts, score)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// currentStreak is zero once a player has missed a day, even though the
+// old streak is kept until they solve the next one
+func currentStreak(player *PlayerStreak, today string) int {
+	if player.LastDaily == today || player.LastDaily == previousDay(today) {
+		return player.Streak
+	}
+	return 0
+}
+
+// getLeaderboard lists the best scores for a day, fastest first when two
+// players have the same score. Players who haven't picked a nickname yet
+// are left off.
+func getLeaderboard(db *sql.DB, day string, limit int) ([]LeaderboardEntry, error) {
+	rows, err := db.Query(`select p.nickname, s.seconds, s.attempts, s.score, p.streak, p.last_daily from daily_scores s
+		join players p on p.session = s.session
+		where s.day = ? and p.nickname is not null
+		order by s.score desc, s.seconds, p.nickname limit ?`, day, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []LeaderboardEntry{}
+	for rows.Next() {
+		var seconds int64
+		player := &PlayerStreak{}
+		entry := LeaderboardEntry{Rank: len(entries) + 1}
+		if err := rows.Scan(&entry.Nickname, &seconds, &entry.Attempts, &entry.Score, &player.Streak, &player.LastDaily); err != nil {
+			return nil, err
+		}
+		entry.Time = formatDuration(seconds)
+		entry.Streak = currentStreak(player, day)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func today() string {
+	return time.Now().Format(dayFormat)
+}
+
+// dailyInfo fills in the streak and leaderboard for a daily challenge page
+func dailyInfo(players PlayerStore, day string, session string) *DailyInfo {
+	info := &DailyInfo{Day: day, Today: day == today()}
+
+	player, err := players.GetPlayer(session)
+	if err != nil {
+		log.Println("unable to load streak: ", err)
+		player = &PlayerStreak{}
+	}
+	info.Nickname = player.Nickname
+	info.Streak = currentStreak(player, today())
+	info.Best = player.Best
+
+	info.Leaderboard, err = players.Leaderboard(day, leaderboardSize)
+	if err != nil {
+		log.Println("unable to load leaderboard: ", err)
+	}
+	return info
+}
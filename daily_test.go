@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailyChallenge(t *testing.T) {
+	day := time.Date(2026, time.March, 14, 15, 4, 5, 0, time.UTC)
+	first := dailyChallenge(day)
+	second := dailyChallenge(day.Add(5 * time.Hour))
+	if first.ID != "daily-2026-03-14" {
+		t.Errorf("dailyChallenge() expected id daily-2026-03-14, got: %s", first.ID)
+	}
+	if first.Ciphertext != second.Ciphertext {
+		t.Errorf("dailyChallenge() expected the same puzzle all day")
+	}
+
+	next := dailyChallenge(day.AddDate(0, 0, 1))
+	if next.ID == first.ID {
+		t.Errorf("dailyChallenge() expected a new id the next day")
+	}
+
+	if d, ok := dailyDay(first.ID); !ok || d != "2026-03-14" {
+		t.Errorf("dailyDay() expected 2026-03-14, got: %s", d)
+	}
+	if _, ok := dailyDay("abc123"); ok {
+		t.Errorf("dailyDay() should not treat abc123 as a daily puzzle")
+	}
+	if _, ok := dailyDay("daily-nope"); ok {
+		t.Errorf("dailyDay() should not treat daily-nope as a daily puzzle")
+	}
+}
+
+func TestValidNickname(t *testing.T) {
+	for _, nickname := range []string{"ninja", "Code_Cracker-7", "abc"} {
+		if err := validNickname(nickname); err != nil {
+			t.Errorf("validNickname(%s) expected no error, got: %s", nickname, err)
+		}
+	}
+	for _, nickname := range []string{"", "ab", "Jane Smith", "12345", "averyveryverylongname", "bob!", "zoë"} {
+		if err := validNickname(nickname); err == nil {
+			t.Errorf("validNickname(%s) expected an error", nickname)
+		}
+	}
+}
+
+func TestDailyStreaks(t *testing.T) {
+	testDB := setupTestDB(t)
+	testStore := newSQLStore(testDB)
+
+	recordDailySolve(testStore, "player1", "2026-03-01", 100, 2, 100)
+	recordDailySolve(testStore, "player1", "2026-03-02", 80, 1, 100)
+	player, err := recordDailySolve(testStore, "player1", "2026-03-03", 60, 1, 100)
+	if err != nil {
+		t.Fatalf("error in recordDailySolve(): %s", err)
+	}
+	if player.Streak != 3 || player.Best != 3 {
+		t.Errorf("recordDailySolve() expected a streak of 3, got: %v", player)
+	}
+
+	// solving the same day twice doesn't count twice
+	player, _ = recordDailySolve(testStore, "player1", "2026-03-03", 10, 1, 100)
+	if player.Streak != 3 {
+		t.Errorf("recordDailySolve() expected the streak to stay at 3, got: %v", player.Streak)
+	}
+
+	if streak := currentStreak(player, "2026-03-04"); streak != 3 {
+		t.Errorf("currentStreak() expected 3 the next day, got: %v", streak)
+	}
+	if streak := currentStreak(player, "2026-03-05"); streak != 0 {
+		t.Errorf("currentStreak() expected 0 after missing a day, got: %v", streak)
+	}
+
+	player, _ = recordDailySolve(testStore, "player1", "2026-03-05", 90, 3, 100)
+	if player.Streak != 1 || player.Best != 3 {
+		t.Errorf("recordDailySolve() expected a new streak of 1 and a best of 3, got: %v", player)
+	}
+
+	loaded, err := getPlayerStreak(testDB, "player1")
+	if err != nil {
+		t.Fatalf("error in getPlayerStreak(): %s", err)
+	}
+	if loaded.Streak != 1 || loaded.Best != 3 || loaded.LastDaily != "2026-03-05" {
+		t.Errorf("getPlayerStreak() did not load what was saved: %v", loaded)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	testDB := setupTestDB(t)
+	testStore := newSQLStore(testDB)
+
+	recordDailySolve(testStore, "player1", "2026-03-01", 100, 2, 100)
+	recordDailySolve(testStore, "player2", "2026-03-01", 50, 4, 100)
+	recordDailySolve(testStore, "player3", "2026-03-01", 20, 1, 100)
+
+	if err := setNickname(testStore, "player1", "ninja"); err != nil {
+		t.Fatalf("error in setNickname(): %s", err)
+	}
+	if err := setNickname(testStore, "player2", "Ninja"); err == nil {
+		t.Errorf("setNickname() should not let two players have the same nickname")
+	}
+	if err := setNickname(testStore, "player2", "speedy"); err != nil {
+		t.Fatalf("error in setNickname(): %s", err)
+	}
+	if err := setNickname(testStore, "player1", "ninja"); err != nil {
+		t.Errorf("setNickname() should let a player keep their own nickname: %s", err)
+	}
+
+	entries, err := getLeaderboard(testDB, "2026-03-01", leaderboardSize)
+	if err != nil {
+		t.Fatalf("error in getLeaderboard(): %s", err)
+	}
+	// player3 has no nickname so isn't listed
+	if len(entries) != 2 {
+		t.Fatalf("getLeaderboard() expected 2 entries, got: %v", entries)
+	}
+	if entries[0].Nickname != "speedy" || entries[0].Time != "50s" || entries[1].Nickname != "ninja" {
+		t.Errorf("getLeaderboard() expected speedy then ninja, got: %v", entries)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// dataDirEnv can set the data directory when passing -data isn't handy,
+// like in a container
+const dataDirEnv = "ECC_DATA_DIR"
+
+const (
+	dbFileName   = "ecc.db"
+	certCacheDir = ".cache"
+)
+
+// dataDirPath picks the data directory, the flag wins over the environment
+// and the current directory is the fallback, which is where ecc always kept
+// things before
+func dataDirPath(flagValue string, envValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if envValue != "" {
+		return envValue
+	}
+	return "."
+}
+
+// prepareDataDir makes sure the data directory is somewhere ecc can keep
+// its files, creating it if it isn't there yet. The parent has to exist
+// though, so a typo doesn't quietly make a new tree somewhere.
+func prepareDataDir(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("data directory %s: %w", dir, err)
+	}
+
+	info, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.Mkdir(dir, 0750); err != nil {
+			return "", fmt.Errorf("data directory %s doesn't exist and can't be created: %w", dir, err)
+		}
+		info, err = os.Stat(dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("data directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("data directory %s is a file, not a directory", dir)
+	}
+
+	// the only sure way to know it's writable is to write to it
+	f, err := os.CreateTemp(dir, ".ecc-check-*")
+	if err != nil {
+		return "", fmt.Errorf("data directory %s isn't writable: %w", dir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	return dir, nil
+}
+
+func dbFilePath(dir string) string {
+	return filepath.Join(dir, dbFileName)
+}
+
+func certCachePath(dir string) string {
+	return filepath.Join(dir, certCacheDir)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataDirPath(t *testing.T) {
+	if dir := dataDirPath("/from/flag", "/from/env"); dir != "/from/flag" {
+		t.Errorf("dataDirPath() expected the flag to win, got: %s", dir)
+	}
+	if dir := dataDirPath("", "/from/env"); dir != "/from/env" {
+		t.Errorf("dataDirPath() expected the env var, got: %s", dir)
+	}
+	if dir := dataDirPath("", ""); dir != "." {
+		t.Errorf("dataDirPath() expected the current directory, got: %s", dir)
+	}
+}
+
+func TestPrepareDataDir(t *testing.T) {
+	base := t.TempDir()
+
+	dir, err := prepareDataDir(filepath.Join(base, "data"))
+	if err != nil {
+		t.Fatalf("error in prepareDataDir(): %s", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("prepareDataDir() should have made the directory: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("prepareDataDir() left something behind: %v", entries)
+	}
+	if dbFilePath(dir) != filepath.Join(base, "data", "ecc.db") {
+		t.Errorf("dbFilePath() got the wrong path: %s", dbFilePath(dir))
+	}
+
+	if _, err := prepareDataDir(filepath.Join(base, "missing", "data")); err == nil {
+		t.Errorf("prepareDataDir() expected an error when the parent is missing")
+	}
+
+	file := filepath.Join(base, "file")
+	os.WriteFile(file, []byte("not a directory"), 0644)
+	if _, err := prepareDataDir(file); err == nil {
+		t.Errorf("prepareDataDir() expected an error for a file")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	levelEasy   = "easy"
+	levelMedium = "medium"
+	levelHard   = "hard"
+
+	// scores below these are easy or medium, anything else is hard
+	easyBelow   = 47
+	mediumBelow = 80
+
+	// how many puzzles to try when looking for one at the right level
+	levelTries = 200
+
+	// texts this short are as hard as it gets, this long as easy
+	hardLength = 20
+	easyLength = 80
+)
+
+var challengeLevels = []string{levelEasy, levelMedium, levelHard}
+
+// how hard each cipher is to break, from 0 to 1. A caesar shift only has
+// 25 keys to try and the backwards alphabet only has one.
+var cipherHardness = map[string]float64{
+	challengeCaesar:       0,
+	challengeAtbash:       0.2,
+	challengeSubstitution: 1,
+}
+
+// Difficulty is how hard a cryptogram is to solve. Score goes from 0 to
+// 100, and the other parts are each 0 to 100 as well.
+type Difficulty struct {
+	Score    int
+	Level    string
+	Length   int
+	Coverage int
+	Patterns int
+	Cipher   int
+}
+
+func clamp(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
+
+// rateDifficulty looks at how much the puzzle gives away. Short puzzles
+// have less to go on, puzzles that use lots of different letters have more
+// to work out, and words like "the" could be lots of things while a word
+// like "committee" can only be one.
+func rateDifficulty(challenge *Challenge) *Difficulty {
+	text := lettersOnly(challenge.Ciphertext)
+	length := clamp(float64(easyLength-len(text)) / float64(easyLength-hardLength))
+
+	distinct := make(map[rune]bool)
+	for _, char := range text {
+		distinct[char] = true
+	}
+	coverage := float64(len(distinct)) / 26
+
+	// a word with a pattern nothing else has is a free answer, one with
+	// a hundred possible words hardly helps at all
+	patterns := 0.0
+	cipherWords := words(challenge.Ciphertext)
+	for _, word := range cipherWords {
+		patterns += clamp(math.Log1p(float64(len(wordPatterns[wordPattern(word)]))) / math.Log1p(100))
+	}
+	if len(cipherWords) > 0 {
+		patterns /= float64(len(cipherWords))
+	}
+
+	cipher := cipherHardness[challenge.CipherType]
+
+	score := 25*length + 15*coverage + 25*patterns + 35*cipher
+	difficulty := &Difficulty{
+		Score:    int(math.Round(score)),
+		Length:   int(math.Round(100 * length)),
+		Coverage: int(math.Round(100 * coverage)),
+		Patterns: int(math.Round(100 * patterns)),
+		Cipher:   int(math.Round(100 * cipher)),
+	}
+	difficulty.Level = difficultyLevel(difficulty.Score)
+	return difficulty
+}
+
+func difficultyLevel(score int) string {
+	switch {
+	case score < easyBelow:
+		return levelEasy
+	case score < mediumBelow:
+		return levelMedium
+	default:
+		return levelHard
+	}
+}
+
+func validLevel(level string) bool {
+	for _, l := range challengeLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// generateChallengeLevel keeps making puzzles until one comes out at the
+// level asked for. If none do it settles for the closest one it found.
+func generateChallengeLevel(rng *rand.Rand, level string) *Challenge {
+	if !validLevel(level) {
+		return generateChallenge(rng)
+	}
+
+	target := map[string]int{
+		levelEasy:   easyBelow / 2,
+		levelMedium: (easyBelow + mediumBelow) / 2,
+		levelHard:   (mediumBelow + 100) / 2,
+	}[level]
+
+	var closest *Challenge
+	closestDistance := math.MaxInt
+	for i := 0; i < levelTries; i++ {
+		challenge := generateChallenge(rng)
+		difficulty := rateDifficulty(challenge)
+		if difficulty.Level == level {
+			return challenge
+		}
+
+		distance := difficulty.Score - target
+		if distance < 0 {
+			distance = -distance
+		}
+		if distance < closestDistance {
+			closest = challenge
+			closestDistance = distance
+		}
+	}
+	return closest
+}
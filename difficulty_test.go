@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRateDifficulty(t *testing.T) {
+	long := "The world is full of obvious things which nobody by any chance ever observes."
+	short := "The pen is mightier than the sword."
+
+	easy := rateDifficulty(&Challenge{CipherType: challengeCaesar, Ciphertext: cryptogramEncode(shiftCodeMap(3), long)})
+	hard := rateDifficulty(&Challenge{CipherType: challengeSubstitution, Ciphertext: cryptogramEncode(randomCodeMap(rand.New(rand.NewSource(1))), short)})
+
+	if easy.Level != levelEasy {
+		t.Errorf("rateDifficulty() expected a long caesar shift to be easy, got: %v", easy)
+	}
+	if hard.Level != levelHard {
+		t.Errorf("rateDifficulty() expected a short substitution to be hard, got: %v", hard)
+	}
+	if easy.Cipher != 0 || hard.Cipher != 100 || easy.Length >= hard.Length {
+		t.Errorf("rateDifficulty() parts don't add up: %v %v", easy, hard)
+	}
+}
+
+func TestGenerateChallengeLevel(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for _, level := range challengeLevels {
+		for i := 0; i < 5; i++ {
+			challenge := generateChallengeLevel(rng, level)
+			if difficulty := rateDifficulty(challenge); difficulty.Level != level {
+				t.Errorf("generateChallengeLevel(%s) gave a %s puzzle", level, difficulty.Level)
+			}
+		}
+	}
+
+	if !validLevel(levelMedium) || validLevel("impossible") {
+		t.Errorf("validLevel() expected medium to be valid and impossible not to be")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	exportFormat        = "ecc-code"
+	exportFormatVersion = 1
+	// maxImportSize is far bigger than any real export, it just stops
+	// someone posting a huge file
+	maxImportSize = 64 << 10
+)
+
+// CodeExport is a cipher page written out as JSON, so it can be backed up
+// or moved to another ecc. The secret is never part of it.
+type CodeExport struct {
+	Format        string            `json:"format"`
+	FormatVersion int               `json:"formatVersion"`
+	Path          string            `json:"path"`
+	CipherType    string            `json:"cipherType"`
+	ValueMap      map[string]string `json:"valueMap"`
+	Version       int               `json:"version"`
+	ExportedAt    string            `json:"exportedAt"`
+}
+
+func exportCode(store CodeStore, path string) (*CodeExport, error) {
+	myMap, err := store.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	version, err := store.CurrentVersion(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CodeExport{
+		Format:        exportFormat,
+		FormatVersion: exportFormatVersion,
+		Path:          path,
+		CipherType:    cipherSubstitution,
+		ValueMap:      myMap,
+		Version:       version,
+		ExportedAt:    time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// parseExport reads an exported page back in and checks the map looks like
+// one the save form could have made. The path in the file doesn't have to
+// match, that's how a page gets copied somewhere new.
+func parseExport(data []byte) (*CodeExport, error) {
+	if len(data) == 0 {
+		return nil, errors.New("Pick an exported file to import")
+	}
+	if len(data) > maxImportSize {
+		return nil, errors.New("That file is too big to be an exported cipher")
+	}
+
+	export := &CodeExport{}
+	if err := json.Unmarshal(data, export); err != nil {
+		return nil, errors.New("That file isn't an exported cipher")
+	}
+	if export.Format != exportFormat {
+		return nil, errors.New("That file isn't an exported cipher")
+	}
+	if export.FormatVersion > exportFormatVersion {
+		return nil, errors.New("That file was exported from a newer ecc")
+	}
+	if export.CipherType != cipherSubstitution {
+		return nil, fmt.Errorf("Unknown cipher type %q", export.CipherType)
+	}
+
+	defaultMap := getDefaultCodeMap()
+	if len(export.ValueMap) != len(defaultMap) {
+		return nil, errors.New("The cipher needs a value for every letter from a to z")
+	}
+	for k, v := range export.ValueMap {
+		if _, ok := defaultMap[k]; !ok {
+			return nil, fmt.Errorf("%q isn't a letter from a to z", k)
+		}
+		if utf8.RuneCountInString(v) > 1 {
+			return nil, fmt.Errorf("%s can only stand for one character, not %q", k, v)
+		}
+	}
+	return export, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportCode(t *testing.T) {
+	store := setupTestStore(t)
+
+	export, err := exportCode(store, "testpath")
+	if err != nil {
+		t.Fatalf("error in exportCode(): %s", err)
+	}
+	if export.Format != exportFormat || export.Path != "testpath" || export.Version != 1 || export.ValueMap["a"] != "z" {
+		t.Errorf("exportCode() got the wrong export: %v", export)
+	}
+	if _, err := exportCode(store, "nope"); err != ErrCodeNotFound {
+		t.Errorf("exportCode() expected ErrCodeNotFound, got: %v", err)
+	}
+
+	// what comes out has to go back in
+	b, _ := json.Marshal(export)
+	if strings.Contains(string(b), "password") {
+		t.Errorf("exportCode() should never include the secret: %s", b)
+	}
+	imported, err := parseExport(b)
+	if err != nil {
+		t.Fatalf("error in parseExport(): %s", err)
+	}
+	if len(imported.ValueMap) != 26 || imported.ValueMap["z"] != "a" {
+		t.Errorf("parseExport() got the wrong map: %v", imported.ValueMap)
+	}
+}
+
+func TestParseExportErrors(t *testing.T) {
+	good := func() *CodeExport {
+		return &CodeExport{Format: exportFormat, FormatVersion: 1, CipherType: cipherSubstitution, ValueMap: getDefaultCodeMap()}
+	}
+	cases := map[string]func(*CodeExport){
+		"wrong format": func(e *CodeExport) { e.Format = "something" },
+		"newer format": func(e *CodeExport) { e.FormatVersion = 2 },
+		"wrong cipher": func(e *CodeExport) { e.CipherType = "enigma" },
+		"missing key":  func(e *CodeExport) { delete(e.ValueMap, "q") },
+		"unknown key":  func(e *CodeExport) { delete(e.ValueMap, "q"); e.ValueMap["?"] = "x" },
+		"long value":   func(e *CodeExport) { e.ValueMap["a"] = "zz" },
+	}
+	for name, change := range cases {
+		export := good()
+		change(export)
+		b, _ := json.Marshal(export)
+		if _, err := parseExport(b); err == nil {
+			t.Errorf("parseExport() expected an error for %s", name)
+		}
+	}
+
+	if _, err := parseExport([]byte("not json")); err == nil {
+		t.Errorf("parseExport() expected an error for a file that isn't json")
+	}
+	if _, err := parseExport(nil); err == nil {
+		t.Errorf("parseExport() expected an error for an empty file")
+	}
+}
@@ -1,15 +1,19 @@
 package main
 
 import (
-	"database/sql"
+	"embed"
 	"encoding/json"
 	"html/template"
+	"io"
 	"log"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 type FormResponse struct {
@@ -19,49 +23,67 @@ type FormResponse struct {
 	ValueMap   map[string]string
 	EncodedVal string
 	DecodedVal string
+	Options    CipherOptions
+	Grid       *CipherGrid
+	Steps      []ExplainStep
+	Analysis   *Analysis
+	Solution   *Solution
+	Crack      *CaesarCrack
+	Vigenere   *VigenereReport
+	Workspace  *Workspace
+	Strength   *CipherStrength
+	Challenge  *ChallengePage
+	Patterns   *PatternMatches
+	Crib       *CribReport
+	History    *CodeHistory
+	// DecodedVersion is the old version of the map a message was decoded
+	// with, or 0 for the current one
+	DecodedVersion int
+	// ShareURL links to an encoded message along with the cipher version
+	// it was made with, SharedMessage is that message once it's opened
+	ShareURL      string
+	SharedMessage string
 }
 
-var templates = template.Must(template.ParseGlob("views/*.html"))
+// the views are built in so ecc can run from any directory
+//
+//go:embed views/*.html
+var viewFiles embed.FS
 
-func getIndex(db *sql.DB) http.HandlerFunc {
+var templates = template.Must(template.ParseFS(viewFiles, "views/*.html"))
+
+// touchPage marks the page in the url as used before handling the request
+func touchPage(store CodeStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := store.Touch(chi.URLParam(r, "id")); err != nil {
+				log.Println("unable to touch page: ", err)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func getIndex(store CodeStore) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		templateResponse("index", FormResponse{}, w)
 	})
 }
 
-func getCode(db *sql.DB) http.HandlerFunc {
+func getCode(store CodeStore) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
 		log.Println("getCode() id is: " + id)
 
-		stmt, err := db.Prepare("select valueMap from codes where path = ?")
-		if err != nil {
-			log.Println("unable to prepare SQL statement: ", err)
-			toReturnErr := FormResponse{
-				Path:       id,
-				ErrorMsg:   "Unable to load valueMap",
-				IsClaimed:  isClaimed(db, id),
-				ValueMap:   getDefaultCodeMap(),
-				EncodedVal: "",
-				DecodedVal: "",
-			}
-
-			templateResponse("code", toReturnErr, w)
-			return
-
-		}
-		defer stmt.Close()
-
-		var codeTableDB string
-		err = stmt.QueryRow(id).Scan(&codeTableDB)
+		codeTable, err := store.Get(id)
 		if err != nil {
-			if err == sql.ErrNoRows {
+			if err == ErrCodeNotFound {
 				log.Println("no rows returned, sending default code map")
 				// there were no rows, but otherwise no error occurred
 				// use defalt code map
 				toReturn := FormResponse{
 					Path:       id,
-					IsClaimed:  isClaimed(db, id),
+					IsClaimed:  isClaimed(store, id),
 					ValueMap:   getDefaultCodeMap(),
 					EncodedVal: "",
 					DecodedVal: "",
@@ -69,11 +91,11 @@ func getCode(db *sql.DB) http.HandlerFunc {
 				templateResponse("code", toReturn, w)
 				return
 			} else {
-				log.Println("unable to query row: ", err)
+				log.Println("unable to load valueMap: ", err)
 				toReturnErr := FormResponse{
 					Path:       id,
 					ErrorMsg:   "Unable to load valueMap",
-					IsClaimed:  isClaimed(db, id),
+					IsClaimed:  isClaimed(store, id),
 					ValueMap:   getDefaultCodeMap(),
 					EncodedVal: "",
 					DecodedVal: "",
@@ -83,44 +105,93 @@ func getCode(db *sql.DB) http.HandlerFunc {
 			}
 		}
 
-		var codeTable map[string]string
-		if err = json.Unmarshal([]byte(codeTableDB), &codeTable); err != nil {
-			log.Println("error unmarshaling JSON: ", err)
+		toReturn := FormResponse{
+			Path:       id,
+			IsClaimed:  isClaimed(store, id),
+			ValueMap:   codeTable,
+			EncodedVal: "",
+			DecodedVal: "",
+		}
+		templateResponse("code", toReturn, w)
+	})
+}
+
+func postEncode(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		r.ParseForm()
+
+		myMap, err := store.Get(id)
+		if err != nil {
 			toReturnErr := FormResponse{
 				Path:       id,
 				ErrorMsg:   "Unable to load valueMap",
-				IsClaimed:  isClaimed(db, id),
+				IsClaimed:  isClaimed(store, id),
 				ValueMap:   getDefaultCodeMap(),
 				EncodedVal: "",
 				DecodedVal: "",
 			}
 			templateResponse("code", toReturnErr, w)
 			return
+
+		}
+		opts, err := getCipherOptions(r)
+		if err != nil {
+			toReturnErr := FormResponse{
+				Path:       id,
+				ErrorMsg:   err.Error(),
+				IsClaimed:  isClaimed(store, id),
+				ValueMap:   myMap,
+				EncodedVal: "",
+				DecodedVal: "",
+				Options:    opts,
+			}
+			templateResponse("code", toReturnErr, w)
+			return
+		}
+
+		toEncode := r.FormValue("encInput")
+		result := encodeText(myMap, opts, toEncode)
+
+		// only the substitution map has versions, the other ciphers use a
+		// key word that shouldn't end up in a link
+		shareURL := ""
+		if opts.Type == cipherSubstitution && result.Text != "" {
+			if version, err := store.CurrentVersion(id); err != nil {
+				log.Println("unable to load version: ", err)
+			} else {
+				shareURL = shareLink(r, id, version, result.Text)
+			}
 		}
 
 		toReturn := FormResponse{
 			Path:       id,
-			IsClaimed:  isClaimed(db, id),
-			ValueMap:   codeTable,
-			EncodedVal: "",
+			IsClaimed:  isClaimed(store, id),
+			ValueMap:   myMap,
+			EncodedVal: result.Text,
 			DecodedVal: "",
+			Options:    opts,
+			Grid:       result.Grid,
+			Steps:      result.Steps,
+			ShareURL:   shareURL,
 		}
 		templateResponse("code", toReturn, w)
+
 	})
 }
 
-func postEncode(db *sql.DB) http.HandlerFunc {
+func postDecode(store CodeStore) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
-
 		r.ParseForm()
 
-		myMap, err := getPathCodeMap(db, id)
+		myMap, err := store.Get(id)
 		if err != nil {
 			toReturnErr := FormResponse{
 				Path:       id,
 				ErrorMsg:   "Unable to load valueMap",
-				IsClaimed:  isClaimed(db, id),
+				IsClaimed:  isClaimed(store, id),
 				ValueMap:   getDefaultCodeMap(),
 				EncodedVal: "",
 				DecodedVal: "",
@@ -129,87 +200,558 @@ func postEncode(db *sql.DB) http.HandlerFunc {
 			return
 
 		}
-		toEncode := r.FormValue("encInput")
-		valToReturn := ""
-		if toEncode != "" {
-			for _, char := range toEncode {
-				if string(char) == " " {
-					valToReturn += " "
-				} else {
-					valToReturn += myMap[string(char)]
+		opts, err := getCipherOptions(r)
+		if err != nil {
+			toReturnErr := FormResponse{
+				Path:       id,
+				ErrorMsg:   err.Error(),
+				IsClaimed:  isClaimed(store, id),
+				ValueMap:   myMap,
+				EncodedVal: "",
+				DecodedVal: "",
+				Options:    opts,
+			}
+			templateResponse("code", toReturnErr, w)
+			return
+		}
+
+		// an old message can be decoded with the map as it was back then
+		decodeMap := myMap
+		version := 0
+		if v := strings.TrimSpace(r.FormValue("version")); v != "" {
+			version, err = strconv.Atoi(v)
+			if err == nil {
+				decodeMap, err = store.GetVersion(id, version)
+			}
+			if err != nil {
+				log.Println("unable to load version: ", err)
+				toReturnErr := FormResponse{
+					Path:       id,
+					ErrorMsg:   "There is no version " + v + " of this cipher",
+					IsClaimed:  isClaimed(store, id),
+					ValueMap:   myMap,
+					EncodedVal: "",
+					DecodedVal: "",
+					Options:    opts,
 				}
+				templateResponse("code", toReturnErr, w)
+				return
 			}
 		}
+
+		toDecode := r.FormValue("decInput")
+		result := decodeText(decodeMap, opts, toDecode)
+		toReturn := FormResponse{
+			Path:           id,
+			IsClaimed:      isClaimed(store, id),
+			ValueMap:       myMap,
+			EncodedVal:     "",
+			DecodedVal:     result.Text,
+			Options:        opts,
+			Grid:           result.Grid,
+			Steps:          result.Steps,
+			DecodedVersion: version,
+		}
+		templateResponse("code", toReturn, w)
+
+	})
+}
+
+func postAnalyze(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		r.ParseForm()
+
+		myMap, err := store.Get(id)
+		if err != nil {
+			myMap = getDefaultCodeMap()
+		}
+
 		toReturn := FormResponse{
 			Path:       id,
-			IsClaimed:  isClaimed(db, id),
+			IsClaimed:  isClaimed(store, id),
 			ValueMap:   myMap,
-			EncodedVal: valToReturn,
+			EncodedVal: "",
 			DecodedVal: "",
+			Analysis:   analyzeText(r.FormValue("analyzeInput")),
 		}
 		templateResponse("code", toReturn, w)
 
 	})
 }
 
-func postDecode(db *sql.DB) http.HandlerFunc {
+func postSolve(store CodeStore) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
 		r.ParseForm()
 
-		myMap, err := getPathCodeMap(db, id)
+		myMap, err := store.Get(id)
+		if err != nil {
+			myMap = getDefaultCodeMap()
+		}
+
+		solution, err := solveSubstitution(r.FormValue("solveInput"))
 		if err != nil {
 			toReturnErr := FormResponse{
 				Path:       id,
-				ErrorMsg:   "Unable to load valueMap",
-				IsClaimed:  isClaimed(db, id),
-				ValueMap:   getDefaultCodeMap(),
+				ErrorMsg:   err.Error(),
+				IsClaimed:  isClaimed(store, id),
+				ValueMap:   myMap,
 				EncodedVal: "",
 				DecodedVal: "",
 			}
 			templateResponse("code", toReturnErr, w)
 			return
-
 		}
-		toDecode := r.FormValue("decInput")
-		valToReturn := ""
-
-		if toDecode != "" {
-			for _, char := range toDecode {
-				if string(char) == " " {
-					valToReturn += " "
-				} else {
-					for k, v := range myMap {
-						if v == string(char) {
-							valToReturn += k
-						}
-					}
-				}
-			}
 
-		}
 		toReturn := FormResponse{
 			Path:       id,
-			IsClaimed:  isClaimed(db, id),
+			IsClaimed:  isClaimed(store, id),
 			ValueMap:   myMap,
 			EncodedVal: "",
-			DecodedVal: valToReturn,
+			DecodedVal: "",
+			Solution:   solution,
 		}
 		templateResponse("code", toReturn, w)
 
 	})
 }
 
-func postSaveMap(db *sql.DB) http.HandlerFunc {
+func getCrack(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		templateResponse("crack", FormResponse{Path: id}, w)
+	})
+}
+
+func postCrack(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		r.ParseForm()
+
+		toCrack := r.FormValue("crackInput")
+		crack := crackCaesar(toCrack)
+		if crack == nil {
+			toReturnErr := FormResponse{
+				Path:     id,
+				ErrorMsg: "There is nothing to crack",
+			}
+			templateResponse("crack", toReturnErr, w)
+			return
+		}
+
+		toReturn := FormResponse{
+			Path:  id,
+			Crack: crack,
+		}
+		templateResponse("crack", toReturn, w)
+
+	})
+}
+
+func getVigenere(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		templateResponse("vigenere", FormResponse{Path: id}, w)
+	})
+}
+
+func postVigenere(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		r.ParseForm()
+
+		keyLength := 0
+		if length := strings.TrimSpace(r.FormValue("keyLength")); length != "" {
+			var err error
+			keyLength, err = strconv.Atoi(length)
+			if err != nil || keyLength < 1 {
+				toReturnErr := FormResponse{
+					Path:     id,
+					ErrorMsg: "Key length must be a number bigger than 0",
+				}
+				templateResponse("vigenere", toReturnErr, w)
+				return
+			}
+		}
+
+		input := r.FormValue("vigenereInput")
+		if utf8.RuneCountInString(input) > vigenereMaxInput {
+			toReturnErr := FormResponse{
+				Path:     id,
+				ErrorMsg: "Messages can be up to " + strconv.Itoa(vigenereMaxInput) + " characters long",
+			}
+			templateResponse("vigenere", toReturnErr, w)
+			return
+		}
+
+		report := analyzeVigenere(input, keyLength)
+		if report == nil {
+			toReturnErr := FormResponse{
+				Path:     id,
+				ErrorMsg: "There is nothing to analyze",
+			}
+			templateResponse("vigenere", toReturnErr, w)
+			return
+		}
+
+		toReturn := FormResponse{
+			Path:     id,
+			Vigenere: report,
+		}
+		templateResponse("vigenere", toReturn, w)
+
+	})
+}
+
+func getWorkspace(players PlayerStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		session := getSessionID(w, r)
+
+		workspace, err := players.GetWorkspace(session, id)
+		if err != nil {
+			log.Println("unable to load workspace: ", err)
+			toReturnErr := FormResponse{
+				Path:      id,
+				ErrorMsg:  "Unable to load your workspace",
+				Workspace: workspace,
+			}
+			templateResponse("workspace", toReturnErr, w)
+			return
+		}
+
+		toReturn := FormResponse{
+			Path:      id,
+			Workspace: workspace,
+		}
+
+		if word := r.URL.Query().Get("pattern"); word != "" {
+			matches, err := lookupPattern(wordPatterns, word)
+			if err != nil {
+				toReturn.ErrorMsg = err.Error()
+			} else {
+				if workspace.Solved > 0 {
+					matches.filterGuesses(wordPatterns, workspace.Guesses)
+				}
+				toReturn.Patterns = matches
+			}
+		}
+		templateResponse("workspace", toReturn, w)
+	})
+}
+
+func getPatterns() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		matches, err := lookupPattern(wordPatterns, r.URL.Query().Get("word"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(matches); err != nil {
+			log.Println("unable to write pattern matches: ", err)
+		}
+	})
+}
+
+func postWorkspace(players PlayerStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		session := getSessionID(w, r)
+		r.ParseForm()
+
+		workspace, err := players.GetWorkspace(session, id)
+		if err != nil {
+			log.Println("unable to load workspace: ", err)
+		}
+
+		var crib *CribReport
+		errorMsg := ""
+		switch r.FormValue("action") {
+		case "new":
+			workspace = newWorkspace(r.FormValue("ciphertext"), nil)
+		case "guess":
+			workspace.guess(r.FormValue("cipherLetter"), r.FormValue("plainLetter"))
+		case "save":
+			for symbol := range workspace.Guesses {
+				if _, ok := r.Form[symbol]; ok {
+					workspace.guess(symbol, r.FormValue(symbol))
+				}
+			}
+		case "clear":
+			workspace = newWorkspace(workspace.Ciphertext, nil)
+		case "crib", "applyCrib":
+			crib, err = slideCrib(workspace, r.FormValue("crib"))
+			if err != nil {
+				errorMsg = err.Error()
+				break
+			}
+
+			if r.FormValue("action") == "applyCrib" {
+				start, _ := strconv.Atoi(r.FormValue("cribStart"))
+				position, ok := crib.position(start)
+				if !ok {
+					errorMsg = "The crib doesn't fit there any more"
+					break
+				}
+				crib.Applied = position
+			} else if len(crib.Positions) == 1 {
+				// only one place it can go, so fill it in straight away
+				crib.Applied = &crib.Positions[0]
+			}
+			if crib.Applied != nil {
+				workspace.applyCrib(crib.Applied)
+			}
+		}
+		workspace.render()
+
+		if err := players.SaveWorkspace(session, id, workspace); err != nil {
+			log.Println("unable to save workspace: ", err)
+			toReturnErr := FormResponse{
+				Path:      id,
+				ErrorMsg:  "Unable to save your workspace",
+				Workspace: workspace,
+			}
+			templateResponse("workspace", toReturnErr, w)
+			return
+		}
+
+		toReturn := FormResponse{
+			Path:      id,
+			ErrorMsg:  errorMsg,
+			Workspace: workspace,
+			Crib:      crib,
+		}
+		templateResponse("workspace", toReturn, w)
+
+	})
+}
+
+func getChallengeIndex(players PlayerStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		templateResponse("challenge", FormResponse{}, w)
+	})
+}
+
+func postChallengeIndex(players PlayerStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		level := r.FormValue("level")
+		if level != "" && !validLevel(level) {
+			toReturnErr := FormResponse{
+				ErrorMsg: "Unknown difficulty level",
+			}
+			templateResponse("challenge", toReturnErr, w)
+			return
+		}
+
+		challenge := generateChallengeLevel(rand.New(rand.NewSource(time.Now().UnixNano())), level)
+		challenge.ID = randomID(4)
+		challenge.CreatedAt = time.Now().Unix()
+
+		if err := players.CreateChallenge(challenge); err != nil {
+			log.Println("unable to create challenge: ", err)
+			toReturnErr := FormResponse{
+				ErrorMsg: "Unable to create a challenge",
+			}
+			templateResponse("challenge", toReturnErr, w)
+			return
+		}
+
+		http.Redirect(w, r, "/challenge/"+challenge.ID, http.StatusSeeOther)
+	})
+}
+
+func getChallenge(players PlayerStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "challengeID")
+		session := getSessionID(w, r)
+
+		challenge, err := players.GetChallenge(id)
+		if err != nil {
+			log.Println("unable to load challenge: ", err)
+			toReturnErr := FormResponse{
+				ErrorMsg: "That challenge doesn't exist",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			templateResponse("challenge", toReturnErr, w)
+			return
+		}
+
+		player, err := players.StartChallenge(id, session, time.Now().Unix())
+		if err != nil {
+			log.Println("unable to start challenge: ", err)
+			player = &challengePlayer{}
+		}
+
+		toReturn := FormResponse{
+			Challenge: challengePage(players, challenge, session, player),
+		}
+		templateResponse("challenge", toReturn, w)
+	})
+}
+
+func postChallenge(players PlayerStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "challengeID")
+		session := getSessionID(w, r)
+		r.ParseForm()
+
+		challenge, err := players.GetChallenge(id)
+		if err != nil {
+			log.Println("unable to load challenge: ", err)
+			toReturnErr := FormResponse{
+				ErrorMsg: "That challenge doesn't exist",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			templateResponse("challenge", toReturnErr, w)
+			return
+		}
+
+		now := time.Now().Unix()
+		player, err := players.StartChallenge(id, session, now)
+		if err != nil {
+			log.Println("unable to start challenge: ", err)
+			player = &challengePlayer{}
+		}
+
+		guess := r.FormValue("solution")
+		correct := checkSolution(challenge, guess)
+		if player.SolvedAt == 0 {
+			if err := players.RecordAttempt(id, session, correct, now); err != nil {
+				log.Println("unable to record attempt: ", err)
+			}
+			player.Attempts++
+			if correct {
+				player.SolvedAt = now
+				// only today's puzzle counts towards streaks and the leaderboard
+				if day, ok := dailyDay(id); ok && day == today() {
+					hints, err := players.Hints(id, session)
+					if err != nil {
+						log.Println("unable to load hints: ", err)
+					}
+					score := challengeScore(player.Attempts, hints)
+					if _, err := recordDailySolve(players, session, day, now-player.StartedAt, player.Attempts, score); err != nil {
+						log.Println("unable to record daily solve: ", err)
+					}
+				}
+			}
+		}
+
+		page := challengePage(players, challenge, session, player)
+		page.Guess = guess
+		page.WrongGuess = !correct
+		toReturn := FormResponse{
+			Challenge: page,
+		}
+		templateResponse("challenge", toReturn, w)
+
+	})
+}
+
+func postHint(players PlayerStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "challengeID")
+		session := getSessionID(w, r)
+		r.ParseForm()
+
+		challenge, err := players.GetChallenge(id)
+		if err != nil {
+			log.Println("unable to load challenge: ", err)
+			toReturnErr := FormResponse{
+				ErrorMsg: "That challenge doesn't exist",
+			}
+			w.WriteHeader(http.StatusNotFound)
+			templateResponse("challenge", toReturnErr, w)
+			return
+		}
+
+		now := time.Now().Unix()
+		player, err := players.StartChallenge(id, session, now)
+		if err != nil {
+			log.Println("unable to start challenge: ", err)
+			player = &challengePlayer{}
+		}
+
+		// no more hints once it's solved, they wouldn't change anything
+		if player.SolvedAt == 0 {
+			if err := takeHint(players, challenge, session, r.FormValue("hint"), now); err != nil {
+				toReturnErr := FormResponse{
+					ErrorMsg:  err.Error(),
+					Challenge: challengePage(players, challenge, session, player),
+				}
+				templateResponse("challenge", toReturnErr, w)
+				return
+			}
+		}
+
+		http.Redirect(w, r, "/challenge/"+id, http.StatusSeeOther)
+	})
+}
+
+func getDaily(players PlayerStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		challenge, err := getDailyChallenge(players, time.Now())
+		if err != nil {
+			log.Println("unable to load daily challenge: ", err)
+			toReturnErr := FormResponse{
+				ErrorMsg: "Unable to load today's puzzle",
+			}
+			templateResponse("challenge", toReturnErr, w)
+			return
+		}
+
+		http.Redirect(w, r, "/challenge/"+challenge.ID, http.StatusSeeOther)
+	})
+}
+
+func postNickname(players PlayerStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := getSessionID(w, r)
+		r.ParseForm()
+
+		id := r.FormValue("challenge")
+		if _, ok := dailyDay(id); !ok {
+			id = dailyPrefix + today()
+		}
+
+		if err := setNickname(players, session, strings.TrimSpace(r.FormValue("nickname"))); err != nil {
+			toReturnErr := FormResponse{
+				ErrorMsg: err.Error(),
+			}
+			if challenge, err := players.GetChallenge(id); err == nil {
+				player, err := players.StartChallenge(id, session, time.Now().Unix())
+				if err != nil {
+					player = &challengePlayer{}
+				}
+				toReturnErr.Challenge = challengePage(players, challenge, session, player)
+			}
+			templateResponse("challenge", toReturnErr, w)
+			return
+		}
+
+		http.Redirect(w, r, "/challenge/"+id, http.StatusSeeOther)
+	})
+}
+
+func postSaveMap(store CodeStore) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
 		r.ParseForm()
 
 		pathPass := r.FormValue("pathPass")
-		currentPathPass, err := getPathPass(db, id)
+		currentPathPass, err := store.GetSecretHash(id)
+		if err == ErrCodeNotFound {
+			currentPathPass, err = "", nil
+		}
 		if err != nil {
 			log.Println(err)
-			myMap, err := getPathCodeMap(db, id)
+			myMap, err := store.Get(id)
 			if err != nil {
 				log.Println(err)
 				myMap = getDefaultCodeMap()
@@ -217,7 +759,7 @@ func postSaveMap(db *sql.DB) http.HandlerFunc {
 			toReturnErr := FormResponse{
 				Path:       id,
 				ErrorMsg:   "Unable to verify secret",
-				IsClaimed:  isClaimed(db, id),
+				IsClaimed:  isClaimed(store, id),
 				ValueMap:   myMap,
 				EncodedVal: "",
 				DecodedVal: "",
@@ -227,20 +769,17 @@ func postSaveMap(db *sql.DB) http.HandlerFunc {
 
 		}
 
-		if currentPathPass == "" {
-			// path has not been claimed, set initial pass
-			createNewCode(db, id, strings.TrimSpace(pathPass))
-		} else {
+		if currentPathPass != "" {
 
 			if !comparePasswords(currentPathPass, pathPass) {
-				myMap, err := getPathCodeMap(db, id)
+				myMap, err := store.Get(id)
 				if err != nil {
 					myMap = getDefaultCodeMap()
 				}
 
 				toReturnErr := FormResponse{
 					Path:       id,
-					IsClaimed:  isClaimed(db, id),
+					IsClaimed:  isClaimed(store, id),
 					ErrorMsg:   "Invalid Secret",
 					ValueMap:   myMap,
 					EncodedVal: "",
@@ -252,13 +791,17 @@ func postSaveMap(db *sql.DB) http.HandlerFunc {
 			}
 		}
 
-		myMap, err := getPathCodeMap(db, id)
+		// a page that hasn't been claimed fills in the default letters
+		myMap := getDefaultCodeMap()
+		if currentPathPass != "" {
+			myMap, err = store.Get(id)
+		}
 		if err != nil {
 			myMap = getDefaultCodeMap()
 
 			toReturnErr := FormResponse{
 				Path:       id,
-				IsClaimed:  isClaimed(db, id),
+				IsClaimed:  isClaimed(store, id),
 				ErrorMsg:   "Invalid Secret",
 				ValueMap:   myMap,
 				EncodedVal: "",
@@ -272,20 +815,301 @@ func postSaveMap(db *sql.DB) http.HandlerFunc {
 			myMap[k] = r.FormValue(k)
 		}
 
-		setPathCodeMap(db, id, myMap)
+		if currentPathPass == "" {
+			// path has not been claimed, set initial pass along with the map
+			err = store.Create(id, strings.TrimSpace(pathPass), myMap)
+		} else {
+			err = store.UpdateMap(id, myMap)
+		}
+		if err != nil {
+			log.Println("unable to save valueMap: ", err)
+			toReturnErr := FormResponse{
+				Path:       id,
+				IsClaimed:  isClaimed(store, id),
+				ErrorMsg:   "Unable to save valueMap",
+				ValueMap:   myMap,
+				EncodedVal: "",
+				DecodedVal: "",
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			templateResponse("code", toReturnErr, w)
+			return
+		}
 
 		toReturn := FormResponse{
 			Path:       id,
-			IsClaimed:  isClaimed(db, id),
+			IsClaimed:  isClaimed(store, id),
 			ValueMap:   myMap,
 			EncodedVal: "",
 			DecodedVal: "",
+			Strength:   rateCipher(myMap),
 		}
 		templateResponse("code", toReturn, w)
 
 	})
 }
 
+func getExport(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		w.Header().Set("Content-Type", "application/json")
+
+		export, err := exportCode(store, id)
+		if err != nil {
+			if err == ErrCodeNotFound {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "This page hasn't been saved yet"})
+				return
+			}
+			log.Println("unable to export: ", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unable to load valueMap"})
+			return
+		}
+
+		w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(id+".json"))
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(export); err != nil {
+			log.Println("unable to write export: ", err)
+		}
+	})
+}
+
+// readImport gets the exported file from the import form, either pasted
+// in or uploaded
+func readImport(r *http.Request) ([]byte, error) {
+	if document := r.FormValue("document"); document != "" {
+		return []byte(document), nil
+	}
+	file, _, err := r.FormFile("documentFile")
+	if err == http.ErrMissingFile || err == http.ErrNotMultipart {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, maxImportSize+1))
+}
+
+// postImport replaces a page's map with an exported one. It takes the same
+// secret as saving, and claims the page if nobody has yet.
+func postImport(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		r.ParseMultipartForm(maxImportSize)
+
+		myMap, err := store.Get(id)
+		if err != nil {
+			myMap = getDefaultCodeMap()
+		}
+		toReturnErr := FormResponse{
+			Path:      id,
+			IsClaimed: isClaimed(store, id),
+			ValueMap:  myMap,
+		}
+
+		data, err := readImport(r)
+		if err != nil {
+			log.Println("unable to read import: ", err)
+			toReturnErr.ErrorMsg = "Unable to read the file"
+			templateResponse("code", toReturnErr, w)
+			return
+		}
+		export, err := parseExport(data)
+		if err != nil {
+			toReturnErr.ErrorMsg = err.Error()
+			templateResponse("code", toReturnErr, w)
+			return
+		}
+
+		pathPass := r.FormValue("pathPass")
+		currentPathPass, err := store.GetSecretHash(id)
+		claimed := err != ErrCodeNotFound
+		if !claimed {
+			err = nil
+		} else if err == nil && !comparePasswords(currentPathPass, pathPass) {
+			toReturnErr.ErrorMsg = "Invalid Secret"
+			templateResponse("code", toReturnErr, w)
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			toReturnErr.ErrorMsg = "Unable to verify secret"
+			templateResponse("code", toReturnErr, w)
+			return
+		}
+
+		if claimed {
+			err = store.UpdateMap(id, export.ValueMap)
+		} else {
+			err = store.Create(id, strings.TrimSpace(pathPass), export.ValueMap)
+		}
+		if err != nil {
+			log.Println("unable to import: ", err)
+			toReturnErr.ErrorMsg = "Unable to save valueMap"
+			templateResponse("code", toReturnErr, w)
+			return
+		}
+
+		toReturn := FormResponse{
+			Path:      id,
+			IsClaimed: isClaimed(store, id),
+			ValueMap:  export.ValueMap,
+			Strength:  rateCipher(export.ValueMap),
+		}
+		templateResponse("code", toReturn, w)
+	})
+}
+
+// getMessage opens a share link, decoding the message with the version of
+// the cipher it was made with
+func getMessage(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		myMap, err := store.Get(id)
+		if err != nil {
+			if err != ErrCodeNotFound {
+				log.Println("unable to load valueMap: ", err)
+			}
+			myMap = getDefaultCodeMap()
+		}
+
+		version, ciphertext, err := sharedMessage(r.URL.Query())
+		if err != nil {
+			toReturnErr := FormResponse{
+				Path:      id,
+				ErrorMsg:  err.Error(),
+				IsClaimed: isClaimed(store, id),
+				ValueMap:  myMap,
+			}
+			templateResponse("code", toReturnErr, w)
+			return
+		}
+
+		versionMap, err := store.GetVersion(id, version)
+		if err != nil {
+			log.Println("unable to load version: ", err)
+			toReturnErr := FormResponse{
+				Path:          id,
+				ErrorMsg:      "The cipher this message was made with isn't here any more",
+				IsClaimed:     isClaimed(store, id),
+				ValueMap:      myMap,
+				SharedMessage: ciphertext,
+			}
+			templateResponse("code", toReturnErr, w)
+			return
+		}
+
+		opts := CipherOptions{Type: cipherSubstitution, Size: defaultGridSize, Route: routeSpiral}
+		result := decodeText(versionMap, opts, ciphertext)
+		toReturn := FormResponse{
+			Path:           id,
+			IsClaimed:      isClaimed(store, id),
+			ValueMap:       myMap,
+			DecodedVal:     result.Text,
+			Options:        opts,
+			DecodedVersion: version,
+			SharedMessage:  ciphertext,
+		}
+		templateResponse("code", toReturn, w)
+	})
+}
+
+func getHistory(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		versions, err := store.History(id)
+		if err != nil {
+			if err != ErrCodeNotFound {
+				log.Println("unable to load history: ", err)
+			}
+			toReturnErr := FormResponse{
+				Path:      id,
+				ErrorMsg:  "This page hasn't been saved yet, so there's no history",
+				IsClaimed: isClaimed(store, id),
+			}
+			templateResponse("history", toReturnErr, w)
+			return
+		}
+
+		history := buildHistory(versions)
+		from, fromErr := strconv.Atoi(r.URL.Query().Get("from"))
+		to, toErr := strconv.Atoi(r.URL.Query().Get("to"))
+		if fromErr == nil && toErr == nil && !history.compare(from, to) {
+			history.Message = "Pick two versions that exist to compare them"
+		}
+
+		toReturn := FormResponse{
+			Path:      id,
+			IsClaimed: isClaimed(store, id),
+			History:   history,
+		}
+		templateResponse("history", toReturn, w)
+	})
+}
+
+// postRevert puts an old map back. It's saved as a new version so the
+// revert can be undone the same way.
+func postRevert(store CodeStore) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		r.ParseForm()
+
+		errorMsg := ""
+		version, err := strconv.Atoi(r.FormValue("version"))
+		if err != nil {
+			errorMsg = "Pick a version to go back to"
+		}
+
+		var oldMap map[string]string
+		if errorMsg == "" {
+			currentPathPass, err := store.GetSecretHash(id)
+			if err != nil {
+				log.Println("unable to load secret: ", err)
+				errorMsg = "Unable to verify secret"
+			} else if !comparePasswords(currentPathPass, r.FormValue("pathPass")) {
+				errorMsg = "Invalid Secret"
+			}
+		}
+		if errorMsg == "" {
+			oldMap, err = store.GetVersion(id, version)
+			if err != nil {
+				log.Println("unable to load version: ", err)
+				errorMsg = "There is no version " + strconv.Itoa(version) + " of this cipher"
+			}
+		}
+		if errorMsg == "" {
+			if err := store.UpdateMap(id, oldMap); err != nil {
+				log.Println("unable to revert: ", err)
+				errorMsg = "Unable to save valueMap"
+			}
+		}
+
+		history := &CodeHistory{}
+		versions, err := store.History(id)
+		if err != nil {
+			log.Println("unable to load history: ", err)
+		} else {
+			history = buildHistory(versions)
+		}
+		if errorMsg == "" {
+			history.Message = "Went back to version " + strconv.Itoa(version) + ", saved as version " + strconv.Itoa(len(versions))
+		}
+
+		toReturn := FormResponse{
+			Path:      id,
+			IsClaimed: isClaimed(store, id),
+			ErrorMsg:  errorMsg,
+			History:   history,
+		}
+		templateResponse("history", toReturn, w)
+	})
+}
+
 func templateResponse(templateName string, pageBody FormResponse, w http.ResponseWriter) {
 	err := templates.ExecuteTemplate(w, templateName+".html", pageBody)
 
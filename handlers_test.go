@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -54,8 +56,8 @@ func TestGetIndexHandlerChi(t *testing.T) {
 
 func TestGetCodeHandler(t *testing.T) {
 	// Test stuff that doesn't need chi
-	testDB := setupTestDB(t)
-	getCodeHandle := getCode(testDB)
+	testStore := setupTestStore(t)
+	getCodeHandle := getCode(testStore)
 	req := httptest.NewRequest(http.MethodGet, "/testpath", nil)
 	rec := httptest.NewRecorder()
 	getCodeHandle.ServeHTTP(rec, req)
@@ -66,10 +68,10 @@ func TestGetCodeHandler(t *testing.T) {
 }
 
 func TestGetCodeHandlerChi(t *testing.T) {
-	testDB := setupTestDB(t)
+	testStore := setupTestStore(t)
 
 	r := chi.NewRouter()
-	r.Get("/{id}", getCode(testDB))
+	r.Get("/{id}", getCode(testStore))
 
 	req := httptest.NewRequest(http.MethodGet, "/testpath", nil)
 	rec := httptest.NewRecorder()
@@ -102,10 +104,10 @@ func TestGetCodeHandlerChi(t *testing.T) {
 }
 
 func TestPostEncodeHandlerChi(t *testing.T) {
-	testDB := setupTestDB(t)
+	testStore := setupTestStore(t)
 
 	r := chi.NewRouter()
-	r.Post("/{id}/encode", postEncode(testDB))
+	r.Post("/{id}/encode", postEncode(testStore))
 
 	form := url.Values{}
 	form.Add("encInput", "abc")
@@ -138,10 +140,10 @@ func TestPostEncodeHandlerChi(t *testing.T) {
 }
 
 func TestPostDecodeHandlerChi(t *testing.T) {
-	testDB := setupTestDB(t)
+	testStore := setupTestStore(t)
 
 	r := chi.NewRouter()
-	r.Post("/{id}/decode", postDecode(testDB))
+	r.Post("/{id}/decode", postDecode(testStore))
 
 	form := url.Values{}
 	form.Add("decInput", "zyx")
@@ -175,11 +177,691 @@ func TestPostDecodeHandlerChi(t *testing.T) {
 
 }
 
+func TestPostEncodeScytaleHandlerChi(t *testing.T) {
+	testStore := setupTestStore(t)
+
+	r := chi.NewRouter()
+	r.Post("/{id}/encode", postEncode(testStore))
+
+	form := url.Values{}
+	form.Add("encInput", "we are discovered")
+	form.Add("cipherType", "scytale")
+	form.Add("gridSize", "3")
+
+	req := httptest.NewRequest(http.MethodPost, "/testpath/encode", nil)
+	req.Form = form
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	resp := rec.Result()
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("postEncode() expected %v, got %v", http.StatusOK, rec.Code)
+	}
+
+	htmlResp, err := html.Parse(resp.Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	tag := getElementById(htmlResp, "encOutput")
+	nodeOutput := renderNode(tag)
+
+	if !strings.Contains(nodeOutput, "Encoded text: wdveieasrrceeod") {
+		t.Errorf("postEncode() appears to have returned the incorrect page")
+	}
+
+	if getElementById(htmlResp, "cipherGrid") == nil {
+		t.Errorf("postEncode() should have returned the cipher grid, but it didn't")
+	}
+
+	// bad grid size
+	form.Set("gridSize", "zero")
+	req = httptest.NewRequest(http.MethodPost, "/testpath/encode", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if getElementById(htmlResp, "errMsg") == nil {
+		t.Errorf("postEncode() should have returned an error message, but it didn't")
+	}
+
+	// a grid far bigger than the text shouldn't try to allocate all of it
+	for _, cipherType := range []string{"scytale", "route"} {
+		form = url.Values{}
+		form.Add("encInput", "hello")
+		form.Add("cipherType", cipherType)
+		form.Add("gridSize", "2000000000")
+		req = httptest.NewRequest(http.MethodPost, "/testpath/encode", nil)
+		req.Form = form
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("postEncode() expected %v for a huge %s grid, got %v", http.StatusOK, cipherType, rec.Code)
+		}
+		htmlResp, err = html.Parse(rec.Result().Body)
+		if err != nil {
+			t.Errorf("html parse error: %v", err)
+		}
+		if !strings.Contains(renderNode(getElementById(htmlResp, "encOutput")), "Encoded text: hello") {
+			t.Errorf("postEncode() expected a huge %s grid to leave hello as it is", cipherType)
+		}
+	}
+}
+
+func TestPostEncodeExplainHandlerChi(t *testing.T) {
+	testStore := setupTestStore(t)
+
+	r := chi.NewRouter()
+	r.Post("/{id}/encode", postEncode(testStore))
+
+	form := url.Values{}
+	form.Add("encInput", "abc")
+
+	req := httptest.NewRequest(http.MethodPost, "/testpath/encode", nil)
+	req.Form = form
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err := html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if getElementById(htmlResp, "explainSteps") != nil {
+		t.Errorf("postEncode() should only explain when asked to")
+	}
+
+	form.Add("explain", "on")
+	req = httptest.NewRequest(http.MethodPost, "/testpath/encode", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	tag := getElementById(htmlResp, "explainSteps")
+	if tag == nil {
+		t.Errorf("postEncode() did not explain the steps")
+	} else if nodeOutput := renderNode(tag); !strings.Contains(nodeOutput, "b has y under it in the cipher table") {
+		t.Errorf("postEncode() appears to have returned the wrong steps: %v", nodeOutput)
+	}
+}
+
+func TestPostDecodeNumbersHandlerChi(t *testing.T) {
+	testStore := setupTestStore(t)
+
+	r := chi.NewRouter()
+	r.Post("/{id}/decode", postDecode(testStore))
+
+	form := url.Values{}
+	form.Add("decInput", "55 53 26")
+	form.Add("cipherType", "nihilist")
+	form.Add("cipherKey", "key")
+
+	req := httptest.NewRequest(http.MethodPost, "/testpath/decode", nil)
+	req.Form = form
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err := html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	nodeOutput := renderNode(getElementById(htmlResp, "decOutput"))
+	if !strings.Contains(nodeOutput, "Decoded text: spy") {
+		t.Errorf("postDecode() appears to have returned the incorrect page")
+	}
+
+	// the nihilist cipher can't work without a key
+	form.Del("cipherKey")
+	req = httptest.NewRequest(http.MethodPost, "/testpath/decode", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if getElementById(htmlResp, "errMsg") == nil {
+		t.Errorf("postDecode() should have returned an error message, but it didn't")
+	}
+}
+
+func TestPostAnalyzeHandlerChi(t *testing.T) {
+	testStore := setupTestStore(t)
+
+	r := chi.NewRouter()
+	r.Post("/{id}/analyze", postAnalyze(testStore))
+
+	form := url.Values{}
+	form.Add("analyzeInput", "gsv jfrxp yildm ulc")
+
+	req := httptest.NewRequest(http.MethodPost, "/testpath/analyze", nil)
+	req.Form = form
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("postAnalyze() expected %v, got %v", http.StatusOK, rec.Code)
+	}
+
+	htmlResp, err := html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	tag := getElementById(htmlResp, "analyzeOutput")
+	if tag == nil {
+		t.Errorf("postAnalyze() did not return the analysis")
+	} else {
+		nodeOutput := renderNode(tag)
+		if !strings.Contains(nodeOutput, "16 symbols counted") || !strings.Contains(nodeOutput, "<svg") {
+			t.Errorf("postAnalyze() appears to have returned the incorrect page: %v", nodeOutput)
+		}
+	}
+}
+
+func TestPostSolveHandlerChi(t *testing.T) {
+	testStore := setupTestStore(t)
+
+	r := chi.NewRouter()
+	r.Post("/{id}/solve", postSolve(testStore))
+
+	encoded, _ := substitutionEncodeSteps(getDefaultCodeMap(), solverTestText, false)
+	form := url.Values{}
+	form.Add("solveInput", encoded)
+
+	req := httptest.NewRequest(http.MethodPost, "/testpath/solve", nil)
+	req.Form = form
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("postSolve() expected %v, got %v", http.StatusOK, rec.Code)
+	}
+
+	htmlResp, err := html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	tag := getElementById(htmlResp, "solveOutput")
+	if tag == nil {
+		t.Errorf("postSolve() did not return a solution")
+	} else {
+		nodeOutput := renderNode(tag)
+		if !strings.Contains(nodeOutput, "when the children came home") {
+			t.Errorf("postSolve() did not crack the message: %v", nodeOutput)
+		}
+	}
+}
+
+func TestPostCrackHandlerChi(t *testing.T) {
+	r := chi.NewRouter()
+	r.Post("/{id}/crack", postCrack(nil))
+
+	encoded, _ := substitutionEncodeSteps(shiftCodeMap(3), "attack at dawn by the river", false)
+	form := url.Values{}
+	form.Add("crackInput", encoded)
+
+	req := httptest.NewRequest(http.MethodPost, "/testpath/crack", nil)
+	req.Form = form
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("postCrack() expected %v, got %v", http.StatusOK, rec.Code)
+	}
+
+	htmlResp, err := html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	tag := getElementById(htmlResp, "bestShift")
+	if tag == nil {
+		t.Errorf("postCrack() did not highlight a best shift")
+	} else {
+		nodeOutput := renderNode(tag)
+		if !strings.Contains(nodeOutput, "attack at dawn by the river") {
+			t.Errorf("postCrack() picked the wrong shift: %v", nodeOutput)
+		}
+	}
+}
+
+func TestPostVigenereHandlerChi(t *testing.T) {
+	r := chi.NewRouter()
+	r.Post("/{id}/vigenere", postVigenere(nil))
+
+	encoded, _ := vigenereShiftSteps("lemon", solverTestText, false, false)
+	form := url.Values{}
+	form.Add("vigenereInput", encoded)
+
+	req := httptest.NewRequest(http.MethodPost, "/testpath/vigenere", nil)
+	req.Form = form
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("postVigenere() expected %v, got %v", http.StatusOK, rec.Code)
+	}
+
+	htmlResp, err := html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	tag := getElementById(htmlResp, "vigenereKey")
+	if tag == nil {
+		t.Errorf("postVigenere() did not return a report")
+	} else if nodeOutput := renderNode(tag); !strings.Contains(nodeOutput, "lemon") {
+		t.Errorf("postVigenere() found the wrong key: %v", nodeOutput)
+	}
+
+	// bad key length
+	form.Add("keyLength", "-1")
+	req = httptest.NewRequest(http.MethodPost, "/testpath/vigenere", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if getElementById(htmlResp, "errMsg") == nil {
+		t.Errorf("postVigenere() should have returned an error message, but it didn't")
+	}
+
+	// too long to analyze
+	form = url.Values{}
+	form.Add("vigenereInput", strings.Repeat("a", vigenereMaxInput+1))
+	req = httptest.NewRequest(http.MethodPost, "/testpath/vigenere", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if getElementById(htmlResp, "errMsg") == nil || getElementById(htmlResp, "vigenereKey") != nil {
+		t.Errorf("postVigenere() should have refused a message that's too long")
+	}
+}
+
+func TestWorkspaceHandlerChi(t *testing.T) {
+	testStore := newMemoryStore()
+
+	r := chi.NewRouter()
+	r.Get("/{id}/workspace", getWorkspace(testStore))
+	r.Post("/{id}/workspace", postWorkspace(testStore))
+
+	req := httptest.NewRequest(http.MethodGet, "/testpath/workspace", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("getWorkspace() expected %v, got %v", http.StatusOK, rec.Code)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("getWorkspace() expected a session cookie, got: %v", cookies)
+	}
+
+	post := func(form url.Values) *html.Node {
+		req := httptest.NewRequest(http.MethodPost, "/testpath/workspace", nil)
+		req.AddCookie(cookies[0])
+		req.Form = form
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		htmlResp, err := html.Parse(rec.Result().Body)
+		if err != nil {
+			t.Errorf("html parse error: %v", err)
+		}
+		return htmlResp
+	}
+
+	post(url.Values{"action": {"new"}, "ciphertext": {"gsv xzg"}})
+	post(url.Values{"action": {"guess"}, "cipherLetter": {"g"}, "plainLetter": {"t"}})
+	htmlResp := post(url.Values{"action": {"save"}, "s": {"h"}, "v": {"e"}})
+
+	tag := getElementById(htmlResp, "workspaceOutput")
+	if tag == nil {
+		t.Fatalf("postWorkspace() did not return the workspace")
+	}
+	if nodeOutput := renderNode(tag); !strings.Contains(nodeOutput, ">t</span><span class=\"plain\">h</span><span class=\"plain\">e<") {
+		t.Errorf("postWorkspace() did not fill in the guesses: %v", nodeOutput)
+	}
+
+	// the guesses are still there when the page is loaded again
+	req = httptest.NewRequest(http.MethodGet, "/testpath/workspace", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err := html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	gTag := getElementById(htmlResp, "g")
+	if gTag == nil || !strings.Contains(renderNode(gTag), "value=\"t\"") {
+		t.Errorf("getWorkspace() did not load the saved guesses")
+	}
+
+	// looking up xzg should only suggest words ending in t
+	req = httptest.NewRequest(http.MethodGet, "/testpath/workspace?pattern=xzg", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, _ = html.Parse(rec.Result().Body)
+	tag = getElementById(htmlResp, "patternMatches")
+	if tag == nil {
+		t.Fatalf("getWorkspace() did not look up the pattern")
+	}
+	if nodeOutput := renderNode(tag); !strings.Contains(nodeOutput, `<span class="label label-success">cat</span>`) ||
+		strings.Contains(nodeOutput, `<span class="label label-success">dog</span>`) {
+		t.Errorf("getWorkspace() expected cat to fit and dog not to: %v", nodeOutput)
+	}
+}
+
+func TestWorkspaceCribHandlerChi(t *testing.T) {
+	testStore := newMemoryStore()
+
+	r := chi.NewRouter()
+	r.Post("/{id}/workspace", postWorkspace(testStore))
+
+	var cookie *http.Cookie
+	post := func(form url.Values) *html.Node {
+		req := httptest.NewRequest(http.MethodPost, "/testpath/workspace", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		req.Form = form
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if cookie == nil {
+			cookie = rec.Result().Cookies()[0]
+		}
+
+		htmlResp, err := html.Parse(rec.Result().Body)
+		if err != nil {
+			t.Errorf("html parse error: %v", err)
+		}
+		return htmlResp
+	}
+
+	post(url.Values{"action": {"new"}, "ciphertext": {"zggzxp zg wzdm"}})
+	htmlResp := post(url.Values{"action": {"crib"}, "crib": {"dawn"}})
+
+	tag := getElementById(htmlResp, "cribOutput")
+	if tag == nil {
+		t.Fatalf("postWorkspace() did not slide the crib")
+	}
+	if nodeOutput := renderNode(tag); !strings.Contains(nodeOutput, "it fits in 5 of them") {
+		t.Errorf("postWorkspace() expected dawn to fit in 5 places: %v", nodeOutput)
+	}
+	if getElementById(htmlResp, "cribApplied") != nil {
+		t.Errorf("postWorkspace() should not fill in a crib that fits in more than one place")
+	}
+
+	htmlResp = post(url.Values{"action": {"applyCrib"}, "crib": {"dawn"}, "cribStart": {"9"}})
+	if getElementById(htmlResp, "cribApplied") == nil {
+		t.Errorf("postWorkspace() should have filled in the crib")
+	}
+	wTag := getElementById(htmlResp, "w")
+	if wTag == nil || !strings.Contains(renderNode(wTag), `value="d"`) {
+		t.Errorf("postWorkspace() expected w to be guessed as d")
+	}
+
+	// now that w is d, attack only fits one place and gets filled in
+	htmlResp = post(url.Values{"action": {"crib"}, "crib": {"attack"}})
+	if getElementById(htmlResp, "cribApplied") == nil {
+		t.Errorf("postWorkspace() should have filled in a crib that only fits once")
+	}
+	gTag := getElementById(htmlResp, "g")
+	if gTag == nil || !strings.Contains(renderNode(gTag), `value="t"`) {
+		t.Errorf("postWorkspace() expected g to be guessed as t")
+	}
+}
+
+func TestGetPatternsHandlerChi(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/patterns", getPatterns())
+
+	req := httptest.NewRequest(http.MethodGet, "/patterns?word=QWTTN", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("getPatterns() expected %v, got %v", http.StatusOK, rec.Code)
+	}
+	matches := PatternMatches{}
+	if err := json.NewDecoder(rec.Body).Decode(&matches); err != nil {
+		t.Fatalf("getPatterns() did not return json: %s", err)
+	}
+	if matches.Pattern != "abccd" || matches.Total == 0 {
+		t.Errorf("getPatterns() expected some abccd words, got: %v", matches)
+	}
+	for _, word := range matches.Matches {
+		if wordPattern(word) != "abccd" {
+			t.Errorf("getPatterns() returned %s which doesn't fit", word)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/patterns?word=two+words", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("getPatterns() expected %v, got %v", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestChallengeHandlerChi(t *testing.T) {
+	testStore := newMemoryStore()
+
+	r := chi.NewRouter()
+	r.Post("/challenge", postChallengeIndex(testStore))
+	r.Get("/challenge/{challengeID}", getChallenge(testStore))
+	r.Post("/challenge/{challengeID}", postChallenge(testStore))
+
+	req := httptest.NewRequest(http.MethodPost, "/challenge", nil)
+	req.Form = url.Values{"level": {"hard"}}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("postChallengeIndex() expected %v, got %v", http.StatusSeeOther, rec.Code)
+	}
+	location := rec.Header().Get("Location")
+	challenge, err := testStore.GetChallenge(strings.TrimPrefix(location, "/challenge/"))
+	if err != nil {
+		t.Fatalf("postChallengeIndex() did not save the challenge: %s", err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, location, nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("getChallenge() expected %v, got %v", http.StatusOK, rec.Code)
+	}
+	htmlResp, _ := html.Parse(rec.Result().Body)
+	tag := getElementById(htmlResp, "difficulty")
+	if tag == nil || !strings.Contains(renderNode(tag), "hard") {
+		t.Errorf("getChallenge() should show a hard difficulty")
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("getChallenge() expected a session cookie, got: %v", cookies)
+	}
+
+	guess := func(solution string) *html.Node {
+		req := httptest.NewRequest(http.MethodPost, location, nil)
+		req.AddCookie(cookies[0])
+		req.Form = url.Values{"solution": {solution}}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		htmlResp, err := html.Parse(rec.Result().Body)
+		if err != nil {
+			t.Errorf("html parse error: %v", err)
+		}
+		return htmlResp
+	}
+
+	if getElementById(guess("not even close"), "wrongGuess") == nil {
+		t.Errorf("postChallenge() should have said the guess was wrong")
+	}
+
+	tag = getElementById(guess(challenge.Quote), "solved")
+	if tag == nil {
+		t.Errorf("postChallenge() should have said the challenge was solved")
+	} else if nodeOutput := renderNode(tag); !strings.Contains(nodeOutput, "2 guesses") {
+		t.Errorf("postChallenge() should have counted 2 guesses: %v", nodeOutput)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/challenge/doesnotexist", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("getChallenge() expected %v, got %v", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestDailyChallengeHandlerChi(t *testing.T) {
+	testStore := newMemoryStore()
+
+	r := chi.NewRouter()
+	r.Get("/challenge/daily", getDaily(testStore))
+	r.Post("/challenge/nickname", postNickname(testStore))
+	r.Get("/challenge/{challengeID}", getChallenge(testStore))
+	r.Post("/challenge/{challengeID}", postChallenge(testStore))
+
+	req := httptest.NewRequest(http.MethodGet, "/challenge/daily", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	location := rec.Header().Get("Location")
+	if rec.Code != http.StatusSeeOther || location != "/challenge/daily-"+today() {
+		t.Fatalf("getDaily() expected a redirect to today's puzzle, got %v %v", rec.Code, location)
+	}
+	challenge, err := testStore.GetChallenge("daily-" + today())
+	if err != nil {
+		t.Fatalf("getDaily() did not save today's puzzle: %s", err)
+	}
+
+	send := func(method string, target string, form url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		req.Form = form
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = send(http.MethodGet, location, nil, nil)
+	cookie := rec.Result().Cookies()[0]
+	htmlResp, _ := html.Parse(rec.Result().Body)
+	if getElementById(htmlResp, "nicknameForm") == nil {
+		t.Errorf("getChallenge() should ask for a nickname on the daily puzzle")
+	}
+
+	rec = send(http.MethodPost, "/challenge/nickname", url.Values{"nickname": {"Jane Smith"}, "challenge": {challenge.ID}}, cookie)
+	htmlResp, _ = html.Parse(rec.Result().Body)
+	if getElementById(htmlResp, "errMsg") == nil {
+		t.Errorf("postNickname() should not accept a name with a space in it")
+	}
+
+	rec = send(http.MethodPost, "/challenge/nickname", url.Values{"nickname": {"codebreaker"}, "challenge": {challenge.ID}}, cookie)
+	if rec.Code != http.StatusSeeOther {
+		t.Errorf("postNickname() expected %v, got %v", http.StatusSeeOther, rec.Code)
+	}
+
+	rec = send(http.MethodPost, location, url.Values{"solution": {challenge.Quote}}, cookie)
+	htmlResp, _ = html.Parse(rec.Result().Body)
+	tag := getElementById(htmlResp, "streak")
+	if tag == nil || !strings.Contains(renderNode(tag), "1 day in a row") {
+		t.Errorf("postChallenge() should have started a streak")
+	}
+	tag = getElementById(htmlResp, "leaderboard")
+	if tag == nil || !strings.Contains(renderNode(tag), "codebreaker") {
+		t.Errorf("postChallenge() should have put codebreaker on the leaderboard")
+	}
+}
+
+func TestChallengeHintHandlerChi(t *testing.T) {
+	testStore := newMemoryStore()
+
+	challenge := hintTestChallenge()
+	if err := testStore.CreateChallenge(challenge); err != nil {
+		t.Fatalf("error in createChallenge(): %s", err)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/challenge/{challengeID}", getChallenge(testStore))
+	r.Post("/challenge/{challengeID}", postChallenge(testStore))
+	r.Post("/challenge/{challengeID}/hint", postHint(testStore))
+
+	req := httptest.NewRequest(http.MethodGet, "/challenge/hinttest", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	cookie := rec.Result().Cookies()[0]
+
+	send := func(target string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		req.AddCookie(cookie)
+		req.Form = form
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = send("/challenge/hinttest/hint", url.Values{"hint": {"letter"}})
+	if rec.Code != http.StatusSeeOther {
+		t.Errorf("postHint() expected %v, got %v", http.StatusSeeOther, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/challenge/hinttest", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	htmlResp, _ := html.Parse(rec.Result().Body)
+	tag := getElementById(htmlResp, "revealed")
+	if tag == nil {
+		t.Fatalf("getChallenge() should show the revealed letters")
+	}
+	// o is the most common letter in the puzzle and stands for l
+	if nodeOutput := renderNode(tag); !strings.Contains(nodeOutput, `value="l"`) {
+		t.Errorf("getChallenge() expected l to be revealed: %v", nodeOutput)
+	}
+
+	rec = send("/challenge/hinttest/hint", url.Values{"hint": {"nope"}})
+	htmlResp, _ = html.Parse(rec.Result().Body)
+	if getElementById(htmlResp, "errMsg") == nil {
+		t.Errorf("postHint() should complain about an unknown hint")
+	}
+
+	rec = send("/challenge/hinttest", url.Values{"solution": {"hello all"}})
+	htmlResp, _ = html.Parse(rec.Result().Body)
+	tag = getElementById(htmlResp, "score")
+	if tag == nil || renderNode(tag) != `<b id="score">90</b>` {
+		t.Errorf("postChallenge() expected a score of 90 after one hint")
+	}
+}
+
 func TestPostSaveMapHandlerChi(t *testing.T) {
-	testDB := setupTestDB(t)
+	testStore := setupTestStore(t)
 
 	r := chi.NewRouter()
-	r.Post("/{id}/save", postSaveMap(testDB))
+	r.Post("/{id}/save", postSaveMap(testStore))
 
 	form := url.Values{}
 	for r := 'a'; r <= 'z'; r++ {
@@ -261,6 +943,16 @@ func TestPostSaveMapHandlerChi(t *testing.T) {
 		}
 	}
 
+	strengthTag := getElementById(htmlResp2, "strength")
+	if strengthTag == nil {
+		t.Errorf("postSaveMap() did not rate the cipher")
+	} else {
+		nodeOutput := renderNode(strengthTag)
+		if !strings.Contains(nodeOutput, "2 letters stay the same (a, z)") {
+			t.Errorf("postSaveMap() should have warned about a and z, instead returned: %v", nodeOutput)
+		}
+	}
+
 	// try bad secret
 	form3 := url.Values{}
 	form3.Add("pathPass", "thishouldfail")
@@ -288,6 +980,7 @@ func TestPostSaveMapHandlerChi(t *testing.T) {
 
 	// try claiming an unclaimed page
 	form4 := url.Values{}
+	form4.Add("a", "q")
 	form4.Add("pathPass", "newpass")
 
 	req = httptest.NewRequest(http.MethodPost, "/unclaimedpath/save", nil)
@@ -304,6 +997,309 @@ func TestPostSaveMapHandlerChi(t *testing.T) {
 	if !strings.Contains(string(body4), "This page is claimed") {
 		t.Errorf("postCode() appears to have returned the incorrect page")
 	}
+
+	// the map that was sent is version 1, not a second save on top of the
+	// default one
+	versions, err := testStore.History("unclaimedpath")
+	if err != nil || len(versions) != 1 || versions[0].ValueMap["a"] != "q" {
+		t.Errorf("postSaveMap() expected one version with a as q, got: %v %v", versions, err)
+	}
+}
+
+// failingStore can't save anything
+type failingStore struct {
+	CodeStore
+}
+
+func (store failingStore) Create(path string, secret string, valueMap map[string]string) error {
+	return errors.New("disk full")
+}
+
+func (store failingStore) UpdateMap(path string, valueMap map[string]string) error {
+	return errors.New("disk full")
+}
+
+func TestPostSaveMapFailsHandlerChi(t *testing.T) {
+	r := chi.NewRouter()
+	r.Post("/{id}/save", postSaveMap(failingStore{setupTestStore(t)}))
+
+	for _, path := range []string{"testpath", "unclaimedpath"} {
+		form := url.Values{}
+		form.Add("a", "q")
+		form.Add("pathPass", "password123")
+
+		req := httptest.NewRequest(http.MethodPost, "/"+path+"/save", nil)
+		req.Form = form
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("postSaveMap() expected %v for %s, got %v", http.StatusInternalServerError, path, rec.Code)
+		}
+		htmlResp, _ := html.Parse(rec.Result().Body)
+		errTag := getElementById(htmlResp, "errMsg")
+		if errTag == nil || !strings.Contains(renderNode(errTag), "Unable to save valueMap") {
+			t.Errorf("postSaveMap() should have said the save failed for %s", path)
+		}
+	}
+}
+
+func TestCodeHistoryHandlerChi(t *testing.T) {
+	testStore := setupTestStore(t)
+	myMap, _ := testStore.Get("testpath")
+	myMap["a"] = "q"
+	testStore.UpdateMap("testpath", myMap)
+
+	r := chi.NewRouter()
+	r.Get("/{id}/history", getHistory(testStore))
+	r.Post("/{id}/decode", postDecode(testStore))
+	r.Post("/{id}/revert", postRevert(testStore))
+
+	req := httptest.NewRequest(http.MethodGet, "/testpath/history?from=1&to=2", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err := html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	nodeOutput := renderNode(getElementById(htmlResp, "compare"))
+	if !strings.Contains(nodeOutput, "Version 1 to version 2") || !strings.Contains(nodeOutput, "<kbd>q</kbd>") {
+		t.Errorf("getHistory() should have compared versions 1 and 2, instead returned: %v", nodeOutput)
+	}
+
+	// q only decodes to a with the current map, version 1 had a as z
+	form := url.Values{}
+	form.Add("decInput", "zq")
+	form.Add("version", "1")
+	req = httptest.NewRequest(http.MethodPost, "/testpath/decode", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	nodeOutput = renderNode(getElementById(htmlResp, "decOutput"))
+	if !strings.Contains(nodeOutput, "Decoded text: aj") || !strings.Contains(nodeOutput, "version 1") {
+		t.Errorf("postDecode() should have decoded with version 1, instead returned: %v", nodeOutput)
+	}
+
+	form.Set("version", "9")
+	req = httptest.NewRequest(http.MethodPost, "/testpath/decode", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if getElementById(htmlResp, "errMsg") == nil {
+		t.Errorf("postDecode() should have returned an error for a missing version, but it didn't")
+	}
+
+	// reverting needs the secret
+	form = url.Values{}
+	form.Add("version", "1")
+	form.Add("pathPass", "thishouldfail")
+	req = httptest.NewRequest(http.MethodPost, "/testpath/revert", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if getElementById(htmlResp, "errMsg") == nil {
+		t.Errorf("postRevert() should have returned an error message, but it didn't")
+	}
+	if current, _ := testStore.Get("testpath"); current["a"] != "q" {
+		t.Errorf("postRevert() changed the map without the secret")
+	}
+
+	form.Set("pathPass", "password123")
+	req = httptest.NewRequest(http.MethodPost, "/testpath/revert", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if errTag := getElementById(htmlResp, "errMsg"); errTag != nil {
+		t.Errorf("postRevert() should not have returned an error message, but it did: %v", renderNode(errTag))
+	}
+	nodeOutput = renderNode(getElementById(htmlResp, "historyMsg"))
+	if !strings.Contains(nodeOutput, "saved as version 3") {
+		t.Errorf("postRevert() should have saved version 3, instead returned: %v", nodeOutput)
+	}
+	if current, _ := testStore.Get("testpath"); current["a"] != "z" {
+		t.Errorf("postRevert() expected a to be z again, got: %s", current["a"])
+	}
+}
+
+func TestShareLinkHandlerChi(t *testing.T) {
+	testStore := setupTestStore(t)
+
+	r := chi.NewRouter()
+	r.Post("/{id}/encode", postEncode(testStore))
+	r.Get("/{id}/m", getMessage(testStore))
+
+	form := url.Values{}
+	form.Add("encInput", "abc")
+	req := httptest.NewRequest(http.MethodPost, "/testpath/encode", nil)
+	req.Form = form
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err := html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	shareTag := getElementById(htmlResp, "shareURL")
+	if shareTag == nil {
+		t.Fatalf("postEncode() did not return a share link")
+	}
+	shareURL, _ := getAttribute(shareTag, "value")
+	if !strings.HasSuffix(shareURL, "/testpath/m?c=zyx&v=1") {
+		t.Errorf("postEncode() returned the wrong share link: %v", shareURL)
+	}
+
+	// the link should still read right after the cipher changes
+	myMap, _ := testStore.Get("testpath")
+	myMap["a"], myMap["z"] = "a", "z"
+	testStore.UpdateMap("testpath", myMap)
+
+	req = httptest.NewRequest(http.MethodGet, shareURL, nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	nodeOutput := renderNode(getElementById(htmlResp, "decOutput"))
+	if !strings.Contains(nodeOutput, "Decoded text: abc") || !strings.Contains(nodeOutput, "version 1") {
+		t.Errorf("getMessage() should have decoded with version 1, instead returned: %v", nodeOutput)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/testpath/m?v=7&c=zyx", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if getElementById(htmlResp, "errMsg") == nil {
+		t.Errorf("getMessage() should have returned an error for a missing version, but it didn't")
+	}
+}
+
+func TestExportImportHandlerChi(t *testing.T) {
+	testStore := setupTestStore(t)
+
+	r := chi.NewRouter()
+	r.Get("/{id}/export", getExport(testStore))
+	r.Post("/{id}/import", postImport(testStore))
+
+	req := httptest.NewRequest(http.MethodGet, "/testpath/export", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("getExport() expected %v, got %v", http.StatusOK, rec.Code)
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "testpath.json") {
+		t.Errorf("getExport() should have been a download, got: %v", rec.Header().Get("Content-Disposition"))
+	}
+	var export CodeExport
+	if err := json.NewDecoder(rec.Body).Decode(&export); err != nil {
+		t.Fatalf("getExport() returned bad json: %s", err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/nope/export", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("getExport() expected %v, got %v", http.StatusNotFound, rec.Code)
+	}
+
+	// copy the page somewhere new, with a change
+	export.ValueMap["a"] = "q"
+	b, _ := json.Marshal(export)
+
+	form := url.Values{}
+	form.Add("document", string(b))
+	form.Add("pathPass", "thishouldfail")
+	req = httptest.NewRequest(http.MethodPost, "/testpath/import", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err := html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if getElementById(htmlResp, "errMsg") == nil {
+		t.Errorf("postImport() should have returned an error message, but it didn't")
+	}
+	if current, _ := testStore.Get("testpath"); current["a"] != "z" {
+		t.Errorf("postImport() changed the map without the secret")
+	}
+
+	form.Set("pathPass", "newpass")
+	req = httptest.NewRequest(http.MethodPost, "/copiedpath/import", nil)
+	req.Form = form
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	htmlResp, err = html.Parse(rec.Result().Body)
+	if err != nil {
+		t.Errorf("html parse error: %v", err)
+	}
+	if errTag := getElementById(htmlResp, "errMsg"); errTag != nil {
+		t.Errorf("postImport() should not have returned an error message, but it did: %v", renderNode(errTag))
+	}
+	if current, _ := testStore.Get("copiedpath"); current["a"] != "q" {
+		t.Errorf("postImport() expected a to be q, got: %s", current["a"])
+	}
+	if !isClaimed(testStore, "copiedpath") {
+		t.Errorf("postImport() should have claimed the new page")
+	}
+}
+
+// touchRecorder is a memory store that remembers which pages were touched
+type touchRecorder struct {
+	*memoryStore
+	touched []string
+}
+
+func (store *touchRecorder) Touch(path string) error {
+	store.touched = append(store.touched, path)
+	return nil
+}
+
+func TestTouchPageHandlerChi(t *testing.T) {
+	store := &touchRecorder{memoryStore: newMemoryStore()}
+
+	r := chi.NewRouter()
+	r.With(touchPage(store)).Get("/{id}", getCode(store))
+
+	req := httptest.NewRequest(http.MethodGet, "/testpath", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("touchPage() expected %v, got %v", http.StatusOK, rec.Code)
+	}
+	if len(store.touched) != 1 || store.touched[0] != "testpath" {
+		t.Errorf("touchPage() should have marked testpath as used, got: %v", store.touched)
+	}
 }
 
 // HELPERS
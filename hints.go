@@ -0,0 +1,203 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+const (
+	hintLetter    = "letter"
+	hintFrequency = "frequency"
+	hintFamily    = "family"
+
+	maxScore     = 100
+	guessPenalty = 5
+)
+
+// how many points each kind of hint costs
+var hintPenalties = map[string]int{
+	hintLetter:    10,
+	hintFrequency: 5,
+	hintFamily:    15,
+}
+
+var cipherFamilies = map[string]string{
+	challengeSubstitution: "It's a mixed up alphabet. Every letter stands for a different letter, with no pattern to it.",
+	challengeCaesar:       "It's a caesar shift. Every letter has been moved the same number of places along the alphabet.",
+	challengeAtbash:       "It's the alphabet backwards. a is z, b is y, and so on.",
+}
+
+type challengeHint struct {
+	Kind   string
+	Detail string
+}
+
+// ChallengeHints is everything a player has been told about a challenge so
+// far. Reveals has a blank under every cipher letter that hasn't been
+// revealed yet, so it can be shown with the keyMapReadOnly table.
+type ChallengeHints struct {
+	Reveals        map[string]string
+	Revealed       int
+	AllRevealed    bool
+	PuzzleLetters  string
+	EnglishLetters string
+	Family         string
+	Used           int
+	Penalty        int
+	GuessPenalty   int
+	LetterPenalty  int
+	FreqPenalty    int
+	FamilyPenalty  int
+	FrequencyTaken bool
+	FamilyTaken    bool
+}
+
+// cipherLetterCounts counts the letters of the ciphertext, most common first
+func cipherLetterCounts(ciphertext string) []freqCount {
+	grams, _ := countGrams(ciphertext, 1)
+	return grams
+}
+
+func plainLetters(challenge *Challenge) map[string]string {
+	plain := make(map[string]string)
+	for k, v := range challenge.ValueMap {
+		plain[v] = k
+	}
+	return plain
+}
+
+// nextLetterHint picks the most common cipher letter that hasn't been
+// revealed yet, since that is the one that helps the most
+func nextLetterHint(challenge *Challenge, hints []challengeHint) (string, bool) {
+	revealed := make(map[string]bool)
+	for _, hint := range hints {
+		if hint.Kind == hintLetter {
+			revealed[hint.Detail] = true
+		}
+	}
+	for _, count := range cipherLetterCounts(challenge.Ciphertext) {
+		if !revealed[count.gram] {
+			return count.gram, true
+		}
+	}
+	return "", false
+}
+
+func hasHint(hints []challengeHint, kind string) bool {
+	for _, hint := range hints {
+		if hint.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// challengeScore starts at 100 and loses points for every wrong guess and
+// every hint
+func challengeScore(attempts int, hints []challengeHint) int {
+	score := maxScore
+	if attempts > 1 {
+		score -= (attempts - 1) * guessPenalty
+	}
+	for _, hint := range hints {
+		score -= hintPenalties[hint.Kind]
+	}
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+func firstLetters(counts []freqCount, n int) string {
+	letters := []string{}
+	for i := 0; i < len(counts) && i < n; i++ {
+		letters = append(letters, counts[i].gram)
+	}
+	return strings.Join(letters, ", ")
+}
+
+func buildHints(challenge *Challenge, hints []challengeHint) *ChallengeHints {
+	plain := plainLetters(challenge)
+	result := &ChallengeHints{
+		Reveals:       make(map[string]string),
+		Used:          len(hints),
+		GuessPenalty:  guessPenalty,
+		LetterPenalty: hintPenalties[hintLetter],
+		FreqPenalty:   hintPenalties[hintFrequency],
+		FamilyPenalty: hintPenalties[hintFamily],
+	}
+
+	counts := cipherLetterCounts(challenge.Ciphertext)
+	for _, count := range counts {
+		result.Reveals[count.gram] = ""
+	}
+
+	for _, hint := range hints {
+		result.Penalty += hintPenalties[hint.Kind]
+		switch hint.Kind {
+		case hintLetter:
+			result.Reveals[hint.Detail] = plain[hint.Detail]
+			result.Revealed++
+		case hintFrequency:
+			result.FrequencyTaken = true
+			result.PuzzleLetters = firstLetters(counts, 3)
+			result.EnglishLetters = firstLetters(topCounts(englishLetterFreq), 3)
+		case hintFamily:
+			result.FamilyTaken = true
+			result.Family = cipherFamilies[challenge.CipherType]
+		}
+	}
+	result.AllRevealed = result.Revealed == len(result.Reveals)
+	return result
+}
+
+func getChallengeHints(db *sql.DB, id string, session string) ([]challengeHint, error) {
+	rows, err := db.Query("select kind, detail from challenge_hints where challenge_id = ? and session = ? order by created_at, detail", id, session)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hints := []challengeHint{}
+	for rows.Next() {
+		hint := challengeHint{}
+		if err := rows.Scan(&hint.Kind, &hint.Detail); err != nil {
+			return nil, err
+		}
+		hints = append(hints, hint)
+	}
+	return hints, rows.Err()
+}
+
+// takeHint records a hint for a player. Asking for the frequency or cipher
+// family hint a second time doesn't cost anything extra.
+func takeHint(players PlayerStore, challenge *Challenge, session string, kind string, now int64) error {
+	if _, ok := hintPenalties[kind]; !ok {
+		return errors.New("Unknown hint")
+	}
+
+	hints, err := players.Hints(challenge.ID, session)
+	if err != nil {
+		return err
+	}
+
+	detail := ""
+	if kind == hintLetter {
+		letter, ok := nextLetterHint(challenge, hints)
+		if !ok {
+			return errors.New("Every letter has already been revealed")
+		}
+		detail = letter
+	} else if hasHint(hints, kind) {
+		return nil
+	}
+
+	return players.AddHint(challenge.ID, session, kind, detail, now)
+}
+
+func addChallengeHint(db *sql.DB, id string, session string, kind string, detail string, now int64) error {
+	_, err := db.Exec(`insert into challenge_hints(challenge_id, session, kind, detail, created_at) values(?, ?, ?, ?, ?)
+		on conflict(challenge_id, session, kind, detail) do nothing`, id, session, kind, detail, now)
+	return err
+}
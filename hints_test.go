@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+)
+
+func hintTestChallenge() *Challenge {
+	// "hello" with the alphabet backwards is "svool"
+	return &Challenge{
+		ID:         "hinttest",
+		Quote:      "Hello, all.",
+		CipherType: challengeAtbash,
+		ValueMap:   getDefaultCodeMap(),
+		Ciphertext: cryptogramEncode(getDefaultCodeMap(), "Hello, all."),
+	}
+}
+
+func TestNextLetterHint(t *testing.T) {
+	challenge := hintTestChallenge()
+
+	letter, ok := nextLetterHint(challenge, nil)
+	if !ok || letter != "o" {
+		t.Errorf("nextLetterHint() expected the most common letter o, got: %s", letter)
+	}
+
+	hints := []challengeHint{{hintLetter, "o"}, {hintFamily, ""}}
+	letter, _ = nextLetterHint(challenge, hints)
+	if letter != "l" {
+		t.Errorf("nextLetterHint() expected l next, got: %s", letter)
+	}
+
+	hints = []challengeHint{{hintLetter, "o"}, {hintLetter, "l"}, {hintLetter, "s"}, {hintLetter, "v"}, {hintLetter, "z"}}
+	if _, ok := nextLetterHint(challenge, hints); ok {
+		t.Errorf("nextLetterHint() expected no letters left")
+	}
+}
+
+func TestChallengeScore(t *testing.T) {
+	if score := challengeScore(1, nil); score != 100 {
+		t.Errorf("challengeScore() expected 100, got: %v", score)
+	}
+
+	hints := []challengeHint{{hintLetter, "o"}, {hintLetter, "l"}, {hintFrequency, ""}}
+	if score := challengeScore(3, hints); score != 65 {
+		t.Errorf("challengeScore() expected 65, got: %v", score)
+	}
+
+	if score := challengeScore(30, hints); score != 0 {
+		t.Errorf("challengeScore() should not go below 0, got: %v", score)
+	}
+}
+
+func TestBuildHints(t *testing.T) {
+	challenge := hintTestChallenge()
+	hints := buildHints(challenge, []challengeHint{{hintLetter, "o"}, {hintFrequency, ""}, {hintFamily, ""}})
+
+	if len(hints.Reveals) != 5 || hints.Reveals["o"] != "l" || hints.Reveals["s"] != "" {
+		t.Errorf("buildHints() expected o revealed as l and the rest blank, got: %v", hints.Reveals)
+	}
+	if hints.PuzzleLetters != "o, l, s" || hints.EnglishLetters != "e, t, a" {
+		t.Errorf("buildHints() expected o, l, s and e, t, a, got: %s and %s", hints.PuzzleLetters, hints.EnglishLetters)
+	}
+	if hints.Family != cipherFamilies[challengeAtbash] {
+		t.Errorf("buildHints() expected the atbash description, got: %s", hints.Family)
+	}
+	if hints.Used != 3 || hints.Penalty != 30 || hints.AllRevealed {
+		t.Errorf("buildHints() expected 3 hints costing 30, got: %v %v", hints.Used, hints.Penalty)
+	}
+}
+
+func TestTakeHint(t *testing.T) {
+	testDB := setupTestDB(t)
+	testStore := newSQLStore(testDB)
+	challenge := hintTestChallenge()
+
+	takeHint(testStore, challenge, "player1", hintLetter, 1)
+	takeHint(testStore, challenge, "player1", hintLetter, 2)
+	takeHint(testStore, challenge, "player1", hintFamily, 3)
+	takeHint(testStore, challenge, "player1", hintFamily, 4)
+	takeHint(testStore, challenge, "player2", hintLetter, 5)
+
+	if err := takeHint(testStore, challenge, "player1", "answer", 6); err == nil {
+		t.Errorf("takeHint() expected an error for an unknown hint")
+	}
+
+	hints, err := getChallengeHints(testDB, challenge.ID, "player1")
+	if err != nil {
+		t.Fatalf("error in getChallengeHints(): %s", err)
+	}
+	if len(hints) != 3 || hints[0].Detail != "o" || hints[1].Detail != "l" || hints[2].Kind != hintFamily {
+		t.Errorf("getChallengeHints() expected o, l and the family hint, got: %v", hints)
+	}
+}
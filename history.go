@@ -0,0 +1,108 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// CodeVersion is one of the maps a path has had. Version 1 is the map it
+// was claimed with and every save adds one.
+type CodeVersion struct {
+	Version   int
+	CreatedAt int64
+	ValueMap  map[string]string
+}
+
+// MapChange is one letter that stands for something different between two
+// versions of a map.
+type MapChange struct {
+	Letter string
+	From   string
+	To     string
+}
+
+// HistoryEntry is a version along with what changed since the one before.
+type HistoryEntry struct {
+	CodeVersion
+	Saved   string
+	Changes []MapChange
+	Current bool
+}
+
+// CodeHistory is everything the history page shows. Compare is filled in
+// when two versions have been picked to diff.
+type CodeHistory struct {
+	Entries []HistoryEntry
+	From    int
+	To      int
+	Compare []MapChange
+	Message string
+}
+
+// diffMaps lists the letters that changed, in alphabetical order
+func diffMaps(from map[string]string, to map[string]string) []MapChange {
+	letters := make(map[string]bool)
+	for k := range from {
+		letters[k] = true
+	}
+	for k := range to {
+		letters[k] = true
+	}
+
+	changes := []MapChange{}
+	for letter := range letters {
+		if from[letter] != to[letter] {
+			changes = append(changes, MapChange{Letter: letter, From: from[letter], To: to[letter]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Letter < changes[j].Letter
+	})
+	return changes
+}
+
+func savedAt(createdAt int64) string {
+	if createdAt == 0 {
+		return "before history was kept"
+	}
+	return time.Unix(createdAt, 0).Format("Jan 2, 2006 3:04pm")
+}
+
+// buildHistory puts the newest version first, since that's usually the one
+// people are looking for
+func buildHistory(versions []CodeVersion) *CodeHistory {
+	history := &CodeHistory{}
+	for i := len(versions) - 1; i >= 0; i-- {
+		entry := HistoryEntry{
+			CodeVersion: versions[i],
+			Saved:       savedAt(versions[i].CreatedAt),
+			Current:     i == len(versions)-1,
+		}
+		if i > 0 {
+			entry.Changes = diffMaps(versions[i-1].ValueMap, versions[i].ValueMap)
+		}
+		history.Entries = append(history.Entries, entry)
+	}
+	return history
+}
+
+// compare fills in the diff between two versions, if they both exist
+func (history *CodeHistory) compare(from int, to int) bool {
+	var fromMap, toMap map[string]string
+	for _, entry := range history.Entries {
+		if entry.Version == from {
+			fromMap = entry.ValueMap
+		}
+		if entry.Version == to {
+			toMap = entry.ValueMap
+		}
+	}
+	if fromMap == nil || toMap == nil {
+		return false
+	}
+
+	history.From = from
+	history.To = to
+	history.Compare = diffMaps(fromMap, toMap)
+	return true
+}
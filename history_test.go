@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDiffMaps(t *testing.T) {
+	from := map[string]string{"a": "z", "b": "y", "c": "x"}
+	to := map[string]string{"a": "z", "b": "q", "c": ""}
+
+	changes := diffMaps(from, to)
+	if len(changes) != 2 {
+		t.Fatalf("diffMaps() expected 2 changes, got: %v", changes)
+	}
+	if changes[0] != (MapChange{Letter: "b", From: "y", To: "q"}) {
+		t.Errorf("diffMaps() expected b to go from y to q, got: %v", changes[0])
+	}
+	if changes[1] != (MapChange{Letter: "c", From: "x", To: ""}) {
+		t.Errorf("diffMaps() expected c to be cleared, got: %v", changes[1])
+	}
+	if changes := diffMaps(from, from); len(changes) != 0 {
+		t.Errorf("diffMaps() expected no changes, got: %v", changes)
+	}
+}
+
+func TestBuildHistory(t *testing.T) {
+	versions := []CodeVersion{
+		{Version: 1, CreatedAt: 0, ValueMap: map[string]string{"a": "z", "b": "y"}},
+		{Version: 2, CreatedAt: 1700000000, ValueMap: map[string]string{"a": "q", "b": "y"}},
+		{Version: 3, CreatedAt: 1700000100, ValueMap: map[string]string{"a": "q", "b": "r"}},
+	}
+
+	history := buildHistory(versions)
+	if len(history.Entries) != 3 {
+		t.Fatalf("buildHistory() expected 3 entries, got: %v", len(history.Entries))
+	}
+	newest, oldest := history.Entries[0], history.Entries[2]
+	if newest.Version != 3 || !newest.Current {
+		t.Errorf("buildHistory() expected version 3 first and current, got: %v", newest.Version)
+	}
+	if len(newest.Changes) != 1 || newest.Changes[0].Letter != "b" {
+		t.Errorf("buildHistory() expected version 3 to change b, got: %v", newest.Changes)
+	}
+	if oldest.Current || oldest.Changes != nil || oldest.Saved != "before history was kept" {
+		t.Errorf("buildHistory() got the wrong first version: %v", oldest)
+	}
+
+	if !history.compare(1, 3) || len(history.Compare) != 2 {
+		t.Errorf("compare() expected 2 changes between 1 and 3, got: %v", history.Compare)
+	}
+	if history.compare(1, 4) {
+		t.Errorf("compare() expected false for a version that doesn't exist")
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// touchEvery is how many seconds go by before using a page again
+	// updates last_used_at, so busy pages aren't a write on every view
+	touchEvery = 60 * 60
+
+	janitorEvery = time.Hour
+
+	expireArchive = "archive"
+	expireRemove  = "remove"
+)
+
+// JanitorConfig says what happens to pages nobody uses any more. Days of 0
+// turns the janitor off.
+type JanitorConfig struct {
+	Days   int
+	Action string
+	DryRun bool
+}
+
+func (config JanitorConfig) validate() error {
+	if config.Days < 0 {
+		return fmt.Errorf("expire days can't be negative, got %v", config.Days)
+	}
+	if config.Action != expireArchive && config.Action != expireRemove {
+		return fmt.Errorf("expire action has to be %s or %s, got %q", expireArchive, expireRemove, config.Action)
+	}
+	return nil
+}
+
+// ExpiredCode is a page that hasn't been used since before the cutoff
+type ExpiredCode struct {
+	Path     string
+	LastUsed int64
+}
+
+// JanitorReport is what one run of the janitor found and did. Started is
+// how many pages from before last_used_at was kept had their clock started.
+type JanitorReport struct {
+	Config  JanitorConfig
+	Cutoff  int64
+	Started int64
+	Expired []ExpiredCode
+	Done    int
+}
+
+func (report *JanitorReport) String() string {
+	var b strings.Builder
+	verb := report.Config.Action + "d"
+	if report.Config.DryRun {
+		verb = "would be " + verb
+	}
+	fmt.Fprintf(&b, "%v pages not used since %s %s", len(report.Expired), time.Unix(report.Cutoff, 0).Format(dayFormat), verb)
+	if !report.Config.DryRun && report.Done != len(report.Expired) {
+		fmt.Fprintf(&b, " (%v were, the rest were used again or failed)", report.Done)
+	}
+	if report.Started > 0 {
+		fmt.Fprintf(&b, ", started the clock on %v older pages", report.Started)
+	}
+	for _, code := range report.Expired {
+		fmt.Fprintf(&b, "\n  %s, last used %s", code.Path, time.Unix(code.LastUsed, 0).Format(dayFormat))
+	}
+	return b.String()
+}
+
+// startActivityClock gives pages from before last_used_at was kept a full
+// period from now, rather than expiring every one of them at once
+func startActivityClock(db *sql.DB, now int64) (int64, error) {
+	result, err := db.Exec("update codes set last_used_at = ? where last_used_at = 0", now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func findExpired(db *sql.DB, cutoff int64) ([]ExpiredCode, error) {
+	rows, err := db.Query("select path, last_used_at from codes where last_used_at > 0 and last_used_at < ? order by last_used_at, path", cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expired := []ExpiredCode{}
+	for rows.Next() {
+		var code ExpiredCode
+		if err := rows.Scan(&code.Path, &code.LastUsed); err != nil {
+			return nil, err
+		}
+		expired = append(expired, code)
+	}
+	return expired, rows.Err()
+}
+
+// archivedCode is everything about a page, kept in archived_codes so it can
+// be put back by hand if someone comes looking for it
+type archivedCode struct {
+	Path       string            `json:"path"`
+	Password   string            `json:"password"`
+	ValueMap   json.RawMessage   `json:"valueMap"`
+	CreatedAt  int64             `json:"createdAt"`
+	LastUsedAt int64             `json:"lastUsedAt"`
+	Versions   []archivedVersion `json:"versions"`
+}
+
+type archivedVersion struct {
+	Version   int             `json:"version"`
+	ValueMap  json.RawMessage `json:"valueMap"`
+	CreatedAt int64           `json:"createdAt"`
+}
+
+func readArchivedCode(tx *sql.Tx, path string, cutoff int64) (*archivedCode, error) {
+	code := &archivedCode{Path: path, Versions: []archivedVersion{}}
+	var valueMap string
+	err := tx.QueryRow("select password, valueMap, created_at, last_used_at from codes where path = ? and last_used_at < ?", path, cutoff).
+		Scan(&code.Password, &valueMap, &code.CreatedAt, &code.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	code.ValueMap = json.RawMessage(valueMap)
+
+	rows, err := tx.Query("select version, valueMap, created_at from code_versions where path = ? order by version", path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version archivedVersion
+		var versionMap string
+		if err := rows.Scan(&version.Version, &versionMap, &version.CreatedAt); err != nil {
+			return nil, err
+		}
+		version.ValueMap = json.RawMessage(versionMap)
+		code.Versions = append(code.Versions, version)
+	}
+	return code, rows.Err()
+}
+
+// expireCode archives or removes one page. It checks the page is still
+// unused inside the transaction, so one that was used since the janitor
+// looked is left alone and false is returned.
+func expireCode(db *sql.DB, path string, cutoff int64, action string, now int64) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	if action == expireArchive {
+		code, err := readArchivedCode(tx, path, cutoff)
+		if err == sql.ErrNoRows {
+			tx.Rollback()
+			return false, nil
+		}
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		b, err := json.Marshal(code)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		if _, err := tx.Exec("insert into archived_codes(path, archived_at, data) values(?, ?, ?)", path, now, string(b)); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	} else {
+		var lastUsed int64
+		err := tx.QueryRow("select last_used_at from codes where path = ? and last_used_at < ?", path, cutoff).Scan(&lastUsed)
+		if err == sql.ErrNoRows {
+			tx.Rollback()
+			return false, nil
+		}
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	if _, err := deleteCode(tx, path); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// runJanitor expires every page not used in the last config.Days days. A
+// dry run only reports what it would do and writes nothing at all.
+func runJanitor(db *sql.DB, config JanitorConfig, now time.Time) (*JanitorReport, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	report := &JanitorReport{
+		Config:  config,
+		Cutoff:  now.AddDate(0, 0, -config.Days).Unix(),
+		Expired: []ExpiredCode{},
+	}
+	if config.Days == 0 {
+		return report, nil
+	}
+
+	// every copy of ecc runs the janitor, they take turns on postgres and
+	// whoever goes second finds the work already done
+	err := withLock(db, janitorLockKey, func() error {
+		return janitorPass(db, report, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func janitorPass(db *sql.DB, report *JanitorReport, now time.Time) error {
+	config := report.Config
+	if !config.DryRun {
+		started, err := startActivityClock(db, now.Unix())
+		if err != nil {
+			return err
+		}
+		report.Started = started
+	}
+
+	expired, err := findExpired(db, report.Cutoff)
+	if err != nil {
+		return err
+	}
+	report.Expired = expired
+	if config.DryRun {
+		return nil
+	}
+
+	for _, code := range expired {
+		done, err := expireCode(db, code.Path, report.Cutoff, config.Action, now.Unix())
+		if err != nil {
+			log.Printf("janitor unable to %s %s: %s", config.Action, code.Path, err)
+			continue
+		}
+		if done {
+			report.Done++
+		}
+	}
+	return nil
+}
+
+// runJanitorCommand is `ecc janitor`, which runs the janitor once. It uses
+// -expire-days and -expire-action unless they're given again after janitor.
+func runJanitorCommand(db *sql.DB, config JanitorConfig, args []string) (*JanitorReport, error) {
+	flags := flag.NewFlagSet("janitor", flag.ExitOnError)
+	flags.IntVar(&config.Days, "days", config.Days, "expire pages nobody has used in this many days")
+	flags.StringVar(&config.Action, "action", config.Action, "what to do with expired pages, "+expireArchive+" or "+expireRemove)
+	flags.BoolVar(&config.DryRun, "dry-run", config.DryRun, "only report which pages would expire")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ecc janitor [-days n] [-action archive|remove] [-dry-run]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if config.Days == 0 {
+		flags.Usage()
+		return nil, errors.New("janitor needs to know how many days a page can go unused, use -days or -expire-days")
+	}
+	return runJanitor(db, config, time.Now())
+}
+
+// janitorLoop runs the janitor when ecc starts and then every janitorEvery
+func janitorLoop(db *sql.DB, config JanitorConfig) {
+	for {
+		report, err := runJanitor(db, config, time.Now())
+		if err != nil {
+			log.Println("janitor failed: ", err)
+		} else if len(report.Expired) > 0 || report.Started > 0 {
+			log.Println("janitor: " + report.String())
+		}
+		time.Sleep(janitorEvery)
+	}
+}
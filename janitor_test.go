@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lastUsed(db *sql.DB, path string) int64 {
+	var lastUsed int64
+	db.QueryRow("select last_used_at from codes where path = ?", path).Scan(&lastUsed)
+	return lastUsed
+}
+
+// setupJanitorDB has testpath used just now, spy last used 100 days ago and
+// secret from before last_used_at was kept
+func setupJanitorDB(t *testing.T, now time.Time) *sql.DB {
+	testDB := setupTestDB(t)
+	createNewCode(testDB, "spy", "password123", getDefaultCodeMap())
+	createNewCode(testDB, "secret", "password123", getDefaultCodeMap())
+	testDB.Exec("update codes set last_used_at = ? where path = ?", now.AddDate(0, 0, -100).Unix(), "spy")
+	testDB.Exec("update codes set last_used_at = 0 where path = ?", "secret")
+	return testDB
+}
+
+func TestJanitorDryRun(t *testing.T) {
+	now := time.Now()
+	testDB := setupJanitorDB(t, now)
+
+	report, err := runJanitor(testDB, JanitorConfig{Days: 90, Action: expireRemove, DryRun: true}, now)
+	if err != nil {
+		t.Fatalf("error in runJanitor(): %s", err)
+	}
+	if len(report.Expired) != 1 || report.Expired[0].Path != "spy" || report.Done != 0 {
+		t.Errorf("runJanitor() expected spy to be reported, got: %v", report)
+	}
+	if !strings.Contains(report.String(), "would be removed") || !strings.Contains(report.String(), "spy") {
+		t.Errorf("runJanitor() got the wrong report: %s", report)
+	}
+	if !isClaimed(newSQLStore(testDB), "spy") || lastUsed(testDB, "secret") != 0 {
+		t.Errorf("runJanitor() changed something on a dry run")
+	}
+}
+
+func TestJanitorArchive(t *testing.T) {
+	now := time.Now()
+	testDB := setupJanitorDB(t, now)
+	store := newSQLStore(testDB)
+
+	report, err := runJanitor(testDB, JanitorConfig{Days: 90, Action: expireArchive}, now)
+	if err != nil {
+		t.Fatalf("error in runJanitor(): %s", err)
+	}
+	if report.Done != 1 || report.Started != 1 {
+		t.Errorf("runJanitor() expected to archive 1 and start 1 clock, got: %v", report)
+	}
+	if isClaimed(store, "spy") {
+		t.Errorf("runJanitor() should have freed up spy")
+	}
+	if _, err := store.History("spy"); err != ErrCodeNotFound {
+		t.Errorf("runJanitor() left the history for spy behind: %v", err)
+	}
+	if !isClaimed(store, "testpath") || !isClaimed(store, "secret") {
+		t.Errorf("runJanitor() expired a page that was still in use")
+	}
+	if lastUsed(testDB, "secret") != now.Unix() {
+		t.Errorf("runJanitor() should have started the clock for secret")
+	}
+
+	var data string
+	if err := testDB.QueryRow("select data from archived_codes where path = ?", "spy").Scan(&data); err != nil {
+		t.Fatalf("runJanitor() didn't archive spy: %s", err)
+	}
+	var archived archivedCode
+	if err := json.Unmarshal([]byte(data), &archived); err != nil {
+		t.Fatalf("runJanitor() archived bad json: %s", err)
+	}
+	if !comparePasswords(archived.Password, "password123") || len(archived.Versions) != 1 {
+		t.Errorf("runJanitor() didn't archive everything about spy: %v", archived)
+	}
+
+	// running again finds nothing new
+	if report, _ := runJanitor(testDB, JanitorConfig{Days: 90, Action: expireArchive}, now); len(report.Expired) != 0 || report.Started != 0 {
+		t.Errorf("runJanitor() expected nothing the second time, got: %v", report)
+	}
+}
+
+func TestJanitorRemove(t *testing.T) {
+	now := time.Now()
+	testDB := setupJanitorDB(t, now)
+
+	report, err := runJanitor(testDB, JanitorConfig{Days: 90, Action: expireRemove}, now)
+	if err != nil || report.Done != 1 {
+		t.Fatalf("runJanitor() expected to remove spy, got: %v %v", report, err)
+	}
+	var archived int
+	testDB.QueryRow("select count(*) from archived_codes").Scan(&archived)
+	if archived != 0 || isClaimed(newSQLStore(testDB), "spy") {
+		t.Errorf("runJanitor() should have removed spy without archiving it")
+	}
+
+	// a page used after the janitor looked is left alone
+	testDB.Exec("update codes set last_used_at = ? where path = ?", now.AddDate(0, 0, -100).Unix(), "testpath")
+	touchCode(testDB, "testpath", now.Unix())
+	if done, err := expireCode(testDB, "testpath", now.AddDate(0, 0, -90).Unix(), expireRemove, now.Unix()); done || err != nil {
+		t.Errorf("expireCode() expected to leave testpath alone, got: %v %v", done, err)
+	}
+}
+
+func TestTouchCode(t *testing.T) {
+	testDB := setupTestDB(t)
+	created := lastUsed(testDB, "testpath")
+
+	// not worth a write so soon after the last one
+	touchCode(testDB, "testpath", created+60)
+	if lastUsed(testDB, "testpath") != created {
+		t.Errorf("touchCode() expected to wait before updating, got: %v", lastUsed(testDB, "testpath"))
+	}
+	touchCode(testDB, "testpath", created+touchEvery+1)
+	if lastUsed(testDB, "testpath") != created+touchEvery+1 {
+		t.Errorf("touchCode() expected an update, got: %v", lastUsed(testDB, "testpath"))
+	}
+}
+
+func TestJanitorConfig(t *testing.T) {
+	bad := []JanitorConfig{
+		{Days: -1, Action: expireArchive},
+		{Days: 30, Action: "shred"},
+	}
+	for _, config := range bad {
+		if err := config.validate(); err == nil {
+			t.Errorf("validate() expected an error for %v", config)
+		}
+	}
+	if report, err := runJanitor(setupTestDB(t), JanitorConfig{Action: expireRemove}, time.Now()); err != nil || len(report.Expired) != 0 {
+		t.Errorf("runJanitor() should do nothing when it's turned off, got: %v %v", report, err)
+	}
+}
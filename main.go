@@ -4,12 +4,12 @@ import (
 	"crypto/tls"
 	"database/sql"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"sync"
 
-	_ "github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/acme"
 	"golang.org/x/crypto/acme/autocert"
 
@@ -18,62 +18,157 @@ import (
 )
 
 var (
-	createDb   = flag.Bool("n", false, "create new, empty database")
+	showSchema = flag.Bool("schema", false, "update the database, print its schema version and exit")
 	runTLS     = flag.Bool("t", false, "run with TLS")
 	portNumber = flag.String("p", "8080", "port number to listen on")
+	dataDir    = flag.String("data", "", "directory to keep the database and certificates in (default $"+dataDirEnv+", or the current directory)")
+	dsn        = flag.String("dsn", "", "postgres connection string to use instead of sqlite (default $"+dsnEnv+")")
+	expireDays = flag.Int("expire-days", 0, "archive or remove pages nobody has used in this many days, 0 keeps them forever")
+	expireWith = flag.String("expire-action", expireArchive, "what to do with expired pages, "+expireArchive+" or "+expireRemove)
+	expireDry  = flag.Bool("expire-dry-run", false, "only log which pages would expire, don't touch them")
 	lock       = sync.Mutex{}
 )
 
 func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: ecc [flags]")
+		fmt.Fprintln(flag.CommandLine.Output(), "       ecc backup [-json] file")
+		fmt.Fprintln(flag.CommandLine.Output(), "       ecc restore [-force] file")
+		fmt.Fprintln(flag.CommandLine.Output(), "       ecc janitor [-days n] [-action archive|remove] [-dry-run]")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
-	var db *sql.DB
-	if *createDb {
-		var err error
-		os.Remove("./ecc.db")
-		db, err = sql.Open("sqlite3", "./ecc.db")
-		if err != nil {
+
+	janitor := JanitorConfig{Days: *expireDays, Action: *expireWith, DryRun: *expireDry}
+	if err := janitor.validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	dir, err := prepareDataDir(dataDirPath(*dataDir, os.Getenv(dataDirEnv)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	dbPath := dbFilePath(dir)
+	if *dsn == "" {
+		*dsn = os.Getenv(dsnEnv)
+	}
+
+	if *dsn != "" && (flag.Arg(0) == "backup" || flag.Arg(0) == "restore") {
+		log.Fatal("backup and restore only work with sqlite, use pg_dump and pg_restore for postgres")
+	}
+
+	// backup reads the database as it is and restore swaps out the file,
+	// neither wants it opened and migrated first
+	if flag.Arg(0) == "backup" {
+		if err := runBackup(dbPath, flag.Args()[1:]); err != nil {
 			log.Fatal(err)
 		}
-
-		sqlStmt := `
-	create table codes (path text not null primary key, password text, valueMap text);
-	delete from codes;
-	`
-		_, err = db.Exec(sqlStmt)
+		return
+	}
+	if flag.Arg(0) == "restore" {
+		version, err := runRestore(dbPath, flag.Args()[1:])
 		if err != nil {
 			log.Fatal(err)
 		}
+		log.Printf("restored %s, database schema is at version %v", dbPath, version)
+		return
+	}
 
-		// insert dummy data
-		createNewCode(db, "dumdum", "password123")
+	var db *sql.DB
+	dbName := dbPath
+	if *dsn != "" {
+		// never log the dsn, it usually has a password in it
+		dbName = "postgres"
+		db, err = openPostgres(*dsn)
 	} else {
-		var err error
-		db, err = sql.Open("sqlite3", "./ecc.db")
+		db, err = sql.Open(sqliteDriver, dbPath)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		log.Fatalf("unable to connect to %s: %s", dbName, err)
+	}
+	log.Printf("using database %s", dbName)
+
+	switch flag.Arg(0) {
+	case "", "janitor":
+	default:
+		flag.Usage()
+		log.Fatalf("unknown command %q", flag.Arg(0))
+	}
+
+	version, err := migrateDB(db)
+	if err != nil {
+		log.Fatalf("unable to set up database %s: %s", dbName, err)
+	}
+	log.Printf("database schema is at version %v", version)
+	if *showSchema {
+		fmt.Println(version)
+		return
+	}
+	// janitor runs it once and prints what happened, it goes after the
+	// migrations since it needs the newest schema
+	if flag.Arg(0) == "janitor" {
+		report, err := runJanitorCommand(db, janitor, flag.Args()[1:])
 		if err != nil {
 			log.Fatal(err)
 		}
+		fmt.Println(report)
+		return
 	}
-	defer db.Close()
+	if janitor.Days > 0 {
+		go janitorLoop(db, janitor)
+	}
+	store := newSQLStore(db)
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RedirectSlashes)
+	// using a page keeps the janitor away from it
+	pages := r.With(touchPage(store))
 
-	r.Get("/", getIndex(db))
-	r.Get("/{id}", getCode(db))
-	r.Post("/{id}/encode", postEncode(db))
-	r.Get("/{id}/encode", getCode(db))
-	r.Post("/{id}/decode", postDecode(db))
-	r.Get("/{id}/decode", getCode(db))
-	r.Post("/{id}/save", postSaveMap(db))
-	r.Get("/{id}/save", getCode(db))
+	r.Get("/", getIndex(store))
+	r.Get("/patterns", getPatterns())
+	r.Get("/challenge", getChallengeIndex(store))
+	r.Post("/challenge", postChallengeIndex(store))
+	r.Get("/challenge/daily", getDaily(store))
+	r.Post("/challenge/nickname", postNickname(store))
+	r.Get("/challenge/{challengeID}", getChallenge(store))
+	r.Post("/challenge/{challengeID}", postChallenge(store))
+	r.Post("/challenge/{challengeID}/hint", postHint(store))
+	pages.Get("/{id}", getCode(store))
+	pages.Post("/{id}/encode", postEncode(store))
+	pages.Get("/{id}/encode", getCode(store))
+	pages.Post("/{id}/decode", postDecode(store))
+	pages.Get("/{id}/decode", getCode(store))
+	pages.Post("/{id}/analyze", postAnalyze(store))
+	pages.Get("/{id}/analyze", getCode(store))
+	pages.Post("/{id}/solve", postSolve(store))
+	pages.Get("/{id}/solve", getCode(store))
+	pages.Post("/{id}/crack", postCrack(store))
+	pages.Get("/{id}/crack", getCrack(store))
+	pages.Post("/{id}/vigenere", postVigenere(store))
+	pages.Get("/{id}/vigenere", getVigenere(store))
+	pages.Post("/{id}/workspace", postWorkspace(store))
+	pages.Get("/{id}/workspace", getWorkspace(store))
+	pages.Post("/{id}/save", postSaveMap(store))
+	pages.Get("/{id}/save", getCode(store))
+	pages.Get("/{id}/m", getMessage(store))
+	pages.Get("/{id}/export", getExport(store))
+	pages.Post("/{id}/import", postImport(store))
+	pages.Get("/{id}/import", getCode(store))
+	pages.Get("/{id}/history", getHistory(store))
+	pages.Post("/{id}/revert", postRevert(store))
+	pages.Get("/{id}/revert", getHistory(store))
 
 	// Start server
 	if *runTLS {
 		autoTLSManager := autocert.Manager{
 			Prompt: autocert.AcceptTOS,
-			Cache:  autocert.DirCache(".cache"),
+			Cache:  autocert.DirCache(certCachePath(dir)),
 			//HostPolicy: autocert.HostWhitelist("<DOMAIN>"),
 		}
 		s := http.Server{
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// every change to the database goes in migrations/ as a new file named
+// NNNN_what_it_does.sql. They run in order and each one only ever runs once,
+// so never edit one that has been released, add another one instead.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// loadMigrations reads the migration files in order, and makes sure the
+// versions go 1, 2, 3... with nothing missing or doubled up
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []migration{}
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		number, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s should be named like 0001_name.sql", file)
+		}
+
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{Version: version, Name: name, SQL: string(b)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("expected migration %v, found %v (%s)", i+1, m.Version, m.Name)
+		}
+	}
+	return migrations, nil
+}
+
+// schemaVersion is the last migration that has been run, or 0 for a brand
+// new database
+func schemaVersion(db *sql.DB) (int, error) {
+	_, err := db.Exec("create table if not exists schema_version (version integer not null primary key, name text, applied_at bigint)")
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	err = db.QueryRow("select coalesce(max(version), 0) from schema_version").Scan(&version)
+	return version, err
+}
+
+// applyMigrations runs every migration newer than the database, each in its
+// own transaction, and returns the version the database ends up at. Copies
+// of ecc starting together on postgres take turns, and the version is only
+// read once it's this one's turn so nothing runs twice.
+func applyMigrations(db *sql.DB, migrations []migration) (int, error) {
+	var version int
+	err := withLock(db, migrateLockKey, func() error {
+		var err error
+		version, err = applyMigrationsLocked(db, migrations)
+		return err
+	})
+	return version, err
+}
+
+func applyMigrationsLocked(db *sql.DB, migrations []migration) (int, error) {
+	version, err := schemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	if version > len(migrations) {
+		return version, fmt.Errorf("database is at schema version %v but this ecc only knows up to %v", version, len(migrations))
+	}
+
+	for _, m := range migrations[version:] {
+		tx, err := db.Begin()
+		if err != nil {
+			return version, err
+		}
+
+		if _, err := tx.Exec(m.SQL); err != nil {
+			tx.Rollback()
+			return version, fmt.Errorf("migration %v (%s) failed: %w", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec("insert into schema_version(version, name, applied_at) values(?, ?, ?)", m.Version, m.Name, time.Now().Unix())
+		if err != nil {
+			tx.Rollback()
+			return version, err
+		}
+		if err := tx.Commit(); err != nil {
+			return version, err
+		}
+
+		version = m.Version
+		log.Printf("applied migration %v (%s)", m.Version, m.Name)
+	}
+	return version, nil
+}
+
+// migrateDB brings the database up to date with the migrations built in
+func migrateDB(db *sql.DB) (int, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return 0, err
+	}
+	return applyMigrations(db, migrations)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatalf("error in loadMigrations(): %s", err)
+	}
+	if len(migrations) == 0 || migrations[0].Name != "codes" {
+		t.Errorf("loadMigrations() expected the codes table first, got: %v", migrations)
+	}
+
+	fsys := fstest.MapFS{
+		"migrations/0002_second.sql": {Data: []byte("create table b (id integer);")},
+		"migrations/0001_first.sql":  {Data: []byte("create table a (id integer);")},
+	}
+	migrations, err = loadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("error in loadMigrations(): %s", err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "first" || migrations[1].Version != 2 {
+		t.Errorf("loadMigrations() expected first then second, got: %v", migrations)
+	}
+
+	bad := []fstest.MapFS{
+		{"migrations/0001_first.sql": {}, "migrations/0003_third.sql": {}},
+		{"migrations/0001_first.sql": {}, "migrations/001_again.sql": {}},
+		{"migrations/first.sql": {}},
+	}
+	for _, fsys := range bad {
+		if _, err := loadMigrations(fsys); err == nil {
+			t.Errorf("loadMigrations() expected an error for %v", fsys)
+		}
+	}
+}
+
+func TestApplyMigrations(t *testing.T) {
+	db, err := sql.Open(sqliteDriver, ":memory:")
+	if err != nil {
+		t.Fatalf("unable to create testing db: %s", err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	migrations := []migration{
+		{1, "first", "create table a (id integer);"},
+		{2, "second", "create table b (id integer); insert into b values (1);"},
+	}
+
+	version, err := applyMigrations(db, migrations[:1])
+	if err != nil || version != 1 {
+		t.Fatalf("applyMigrations() expected version 1, got: %v %v", version, err)
+	}
+
+	version, err = applyMigrations(db, migrations)
+	if err != nil || version != 2 {
+		t.Fatalf("applyMigrations() expected version 2, got: %v %v", version, err)
+	}
+
+	// running them again doesn't do anything
+	version, err = applyMigrations(db, migrations)
+	if err != nil || version != 2 {
+		t.Errorf("applyMigrations() expected to stay at version 2, got: %v %v", version, err)
+	}
+	var count int
+	db.QueryRow("select count(*) from b").Scan(&count)
+	if count != 1 {
+		t.Errorf("applyMigrations() ran the second migration %v times", count)
+	}
+
+	// a broken migration leaves the version where it was
+	broken := append(migrations, migration{3, "broken", "create table a (id integer);"})
+	version, err = applyMigrations(db, broken)
+	if err == nil || version != 2 {
+		t.Errorf("applyMigrations() expected an error and version 2, got: %v %v", version, err)
+	}
+
+	// a database from a newer ecc shouldn't be touched
+	if _, err := applyMigrations(db, migrations[:1]); err == nil {
+		t.Errorf("applyMigrations() expected an error for a database newer than the migrations")
+	}
+
+	if version, _ := schemaVersion(db); version != 2 {
+		t.Errorf("schemaVersion() expected 2, got: %v", version)
+	}
+}
+
+func TestMigrateDBUpgradesOldDatabase(t *testing.T) {
+	db, err := sql.Open(sqliteDriver, ":memory:")
+	if err != nil {
+		t.Fatalf("unable to create testing db: %s", err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	// what -n used to make
+	db.Exec("create table codes (path text not null primary key, password text, valueMap text);")
+	b, _ := json.Marshal(getDefaultCodeMap())
+	db.Exec("insert into codes(path, password, valueMap) values(?, ?, ?)", "dumdum", "hash", string(b))
+
+	if _, err := migrateDB(db); err != nil {
+		t.Fatalf("error in migrateDB(): %s", err)
+	}
+	store := newSQLStore(db)
+	if !isClaimed(store, "dumdum") {
+		t.Errorf("migrateDB() lost the codes that were already there")
+	}
+	versions, err := store.History("dumdum")
+	if err != nil || len(versions) != 1 || versions[0].Version != 1 {
+		t.Errorf("migrateDB() expected the old map as version 1, got: %v %v", versions, err)
+	}
+}
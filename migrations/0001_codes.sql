@@ -0,0 +1,2 @@
+-- the original table, ecc.db files made with -n already have it
+create table if not exists codes (path text not null primary key, password text, valueMap text);
@@ -0,0 +1 @@
+create table if not exists workspaces (session text not null, path text not null, ciphertext text, guesses text, primary key (session, path));
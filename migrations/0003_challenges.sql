@@ -0,0 +1,2 @@
+create table if not exists challenges (id text not null primary key, quote text, author text, cipher text, valueMap text, ciphertext text, created_at bigint);
+create table if not exists challenge_players (challenge_id text not null, session text not null, started_at bigint, solved_at bigint, attempts integer, primary key (challenge_id, session));
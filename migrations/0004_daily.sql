@@ -0,0 +1,2 @@
+create table if not exists players (session text not null primary key, nickname text unique, streak integer, best_streak integer, last_daily text);
+create table if not exists daily_scores (day text not null, session text not null, seconds integer, attempts integer, score integer, primary key (day, session));
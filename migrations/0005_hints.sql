@@ -0,0 +1 @@
+create table if not exists challenge_hints (challenge_id text not null, session text not null, kind text not null, detail text not null, created_at bigint, primary key (challenge_id, session, kind, detail));
@@ -0,0 +1,3 @@
+create table if not exists code_versions (path text not null, version integer not null, valueMap text, created_at bigint, primary key (path, version));
+-- maps saved before there was any history become version 1, from an unknown time
+insert into code_versions(path, version, valueMap, created_at) select path, 1, valueMap, 0 from codes;
@@ -0,0 +1,6 @@
+-- 0 means the page is from before this was tracked, the janitor starts the
+-- clock on those the first time it runs
+alter table codes add column created_at bigint not null default 0;
+alter table codes add column last_used_at bigint not null default 0;
+-- pages the janitor archived, with their secret and history as json
+create table if not exists archived_codes (path text not null, archived_at bigint not null, data text, primary key (path, archived_at));
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// the two spare columns on the top row of the checkerboard, their digits
+// start the two longer rows underneath
+const (
+	checkerboardBlank1 = 2
+	checkerboardBlank2 = 6
+	checkerboardStop   = "."
+	checkerboardDigits = "/"
+
+	// without a key the top row gets the most common english letters
+	defaultCheckerboardKey = "etaonris"
+)
+
+// mixedAlphabet puts the letters of the key first and then the rest of the
+// alphabet, without repeating any. Leaving out a letter (like j for a
+// polybius square) drops it from the alphabet.
+func mixedAlphabet(key string, leaveOut rune) []rune {
+	seen := make(map[rune]bool)
+	alphabet := []rune{}
+	add := func(char rune) {
+		char = unicode.ToLower(char)
+		if char < 'a' || char > 'z' || char == leaveOut || seen[char] {
+			return
+		}
+		seen[char] = true
+		alphabet = append(alphabet, char)
+	}
+
+	for _, char := range key {
+		add(char)
+	}
+	for char := 'a'; char <= 'z'; char++ {
+		add(char)
+	}
+	return alphabet
+}
+
+// polybiusSquare gives each letter its row and column in a 5x5 square as a
+// two digit number, 11 through 55. i and j share a square.
+func polybiusSquare(key string) map[rune]int {
+	square := make(map[rune]int)
+	for i, char := range mixedAlphabet(key, 'j') {
+		square[char] = (i/5+1)*10 + i%5 + 1
+	}
+	return square
+}
+
+func polybiusLetters(input string) []rune {
+	letters := []rune{}
+	for _, char := range strings.ToLower(input) {
+		if char == 'j' {
+			char = 'i'
+		}
+		if char >= 'a' && char <= 'z' {
+			letters = append(letters, char)
+		}
+	}
+	return letters
+}
+
+// the nihilist cipher adds the square's number for each letter of the key
+// to the square's number for each letter of the message
+func nihilistEncodeSteps(key string, toEncode string, explain bool) (string, []ExplainStep) {
+	square := polybiusSquare(key)
+	keyLetters := polybiusLetters(key)
+	if len(keyLetters) == 0 {
+		return "", nil
+	}
+
+	nums := []string{}
+	steps := []ExplainStep{}
+	for _, char := range strings.ToLower(toEncode) {
+		if char == ' ' {
+			continue
+		}
+		letter := char
+		if letter == 'j' {
+			letter = 'i'
+		}
+		num, ok := square[letter]
+		if !ok {
+			if explain {
+				steps = append(steps, ExplainStep{string(char), "not a letter, left out", ""})
+			}
+			continue
+		}
+
+		keyLetter := keyLetters[len(nums)%len(keyLetters)]
+		out := strconv.Itoa(num + square[keyLetter])
+		nums = append(nums, out)
+		if explain {
+			rule := fmt.Sprintf("%c is %v in the square, plus key letter %c (%v)", letter, num, keyLetter, square[keyLetter])
+			if char == 'j' {
+				rule = "j shares a square with i, " + rule
+			}
+			steps = append(steps, ExplainStep{string(char), rule, out})
+		}
+	}
+	return strings.Join(nums, " "), steps
+}
+
+func nihilistDecodeSteps(key string, toDecode string, explain bool) (string, []ExplainStep) {
+	square := polybiusSquare(key)
+	letters := make(map[int]rune)
+	for char, num := range square {
+		letters[num] = char
+	}
+	keyLetters := polybiusLetters(key)
+	if len(keyLetters) == 0 {
+		return "", nil
+	}
+
+	var valToReturn strings.Builder
+	steps := []ExplainStep{}
+	for i, token := range numberTokens(toDecode) {
+		keyLetter := keyLetters[i%len(keyLetters)]
+		num, err := strconv.Atoi(token)
+		if err != nil {
+			valToReturn.WriteString("?")
+			if explain {
+				steps = append(steps, ExplainStep{token, "too big to be a number from the square", "?"})
+			}
+			continue
+		}
+		char, ok := letters[num-square[keyLetter]]
+		if ok {
+			valToReturn.WriteRune(char)
+		} else {
+			valToReturn.WriteString("?")
+		}
+		if explain {
+			rule := fmt.Sprintf("%v minus key letter %c (%v) is %v", num, keyLetter, square[keyLetter], num-square[keyLetter])
+			if ok {
+				steps = append(steps, ExplainStep{token, rule + fmt.Sprintf(", which is %c in the square", char), string(char)})
+			} else {
+				steps = append(steps, ExplainStep{token, rule + ", which isn't in the square", "?"})
+			}
+		}
+	}
+	return valToReturn.String(), steps
+}
+
+// isASCIIDigit only takes 0 to 9, the decoders slice the digits a byte at
+// a time and other scripts' digits are more than one byte
+func isASCIIDigit(char rune) bool {
+	return char >= '0' && char <= '9'
+}
+
+// numberTokens splits ciphertext into its numbers, anything that isn't a
+// digit counts as a separator
+func numberTokens(input string) []string {
+	return strings.FieldsFunc(input, func(char rune) bool {
+		return !isASCIIDigit(char)
+	})
+}
+
+// checkerboard lays the mixed alphabet out on a straddling checkerboard.
+// The first eight letters go in the top row and get one digit, everything
+// else gets two digits starting with one of the blank columns.
+func checkerboard(key string) map[string]string {
+	if strings.TrimSpace(key) == "" {
+		key = defaultCheckerboardKey
+	}
+
+	cells := []string{}
+	for _, char := range mixedAlphabet(key, 0) {
+		cells = append(cells, string(char))
+	}
+	cells = append(cells, checkerboardStop, checkerboardDigits)
+
+	board := make(map[string]string)
+	col := 0
+	for _, cell := range cells[:8] {
+		if col == checkerboardBlank1 || col == checkerboardBlank2 {
+			col++
+		}
+		board[cell] = strconv.Itoa(col)
+		col++
+	}
+	for i, cell := range cells[8:] {
+		row := checkerboardBlank1
+		if i >= 10 {
+			row = checkerboardBlank2
+		}
+		board[cell] = strconv.Itoa(row) + strconv.Itoa(i%10)
+	}
+	return board
+}
+
+func checkerboardEncodeSteps(key string, toEncode string, explain bool) (string, []ExplainStep) {
+	board := checkerboard(key)
+	var encoded strings.Builder
+	steps := []ExplainStep{}
+	for _, char := range strings.ToLower(toEncode) {
+		switch {
+		case char == ' ':
+		case isASCIIDigit(char):
+			// numbers are written as the digits sign followed by the digit
+			out := board[checkerboardDigits] + string(char)
+			encoded.WriteString(out)
+			if explain {
+				steps = append(steps, ExplainStep{string(char), "numbers are the " + checkerboardDigits + " sign (" + board[checkerboardDigits] + ") and then the number", out})
+			}
+		case board[string(char)] != "":
+			out := board[string(char)]
+			encoded.WriteString(out)
+			if explain {
+				steps = append(steps, ExplainStep{string(char), checkerboardRule(string(char), out), out})
+			}
+		default:
+			if explain {
+				steps = append(steps, ExplainStep{string(char), "not on the checkerboard, left out", ""})
+			}
+		}
+	}
+
+	// spies send the digits in groups of five
+	groups := []string{}
+	digits := encoded.String()
+	for len(digits) > 5 {
+		groups = append(groups, digits[:5])
+		digits = digits[5:]
+	}
+	if digits != "" {
+		groups = append(groups, digits)
+	}
+	return strings.Join(groups, " "), steps
+}
+
+func checkerboardRule(cell string, code string) string {
+	if len(code) == 1 {
+		return cell + " is in the top row, column " + code
+	}
+	return cell + " is in row " + code[:1] + ", column " + code[1:]
+}
+
+func checkerboardDecodeSteps(key string, toDecode string, explain bool) (string, []ExplainStep) {
+	cells := make(map[string]string)
+	for cell, code := range checkerboard(key) {
+		cells[code] = cell
+	}
+
+	digits := strings.Join(numberTokens(toDecode), "")
+	var valToReturn strings.Builder
+	steps := []ExplainStep{}
+	for i := 0; i < len(digits); i++ {
+		code := digits[i : i+1]
+		if code == strconv.Itoa(checkerboardBlank1) || code == strconv.Itoa(checkerboardBlank2) {
+			if i+1 >= len(digits) {
+				valToReturn.WriteString("?")
+				if explain {
+					steps = append(steps, ExplainStep{code, "starts a row but there is no column after it", "?"})
+				}
+				break
+			}
+			i++
+			code += digits[i : i+1]
+		}
+
+		if cells[code] == checkerboardDigits {
+			if i+1 >= len(digits) {
+				valToReturn.WriteString("?")
+				if explain {
+					steps = append(steps, ExplainStep{code, "the " + checkerboardDigits + " sign but there is no number after it", "?"})
+				}
+				break
+			}
+			i++
+			valToReturn.WriteString(digits[i : i+1])
+			if explain {
+				steps = append(steps, ExplainStep{code + digits[i:i+1], "the " + checkerboardDigits + " sign means the next digit is just a number", digits[i : i+1]})
+			}
+			continue
+		}
+		valToReturn.WriteString(cells[code])
+		if explain {
+			steps = append(steps, ExplainStep{code, checkerboardRule(cells[code], code), cells[code]})
+		}
+	}
+	return valToReturn.String(), steps
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPolybiusSquare(t *testing.T) {
+	square := polybiusSquare("")
+	if square['a'] != 11 || square['i'] != 24 || square['k'] != 25 || square['z'] != 55 {
+		t.Errorf("polybiusSquare() expected a=11 i=24 k=25 z=55, got: %v %v %v %v", square['a'], square['i'], square['k'], square['z'])
+	}
+	if _, ok := square['j']; ok {
+		t.Errorf("polybiusSquare() should not have a square for j")
+	}
+
+	square = polybiusSquare("zebras")
+	if square['z'] != 11 || square['e'] != 12 || square['a'] != 15 {
+		t.Errorf("polybiusSquare() expected the key first, got: z=%v e=%v a=%v", square['z'], square['e'], square['a'])
+	}
+}
+
+func TestNihilist(t *testing.T) {
+	// the square starts k e y a b c..., so s=44 p=41 y=13 and k=11 e=12 y=13
+	encoded, _ := nihilistEncodeSteps("key", "spy", false)
+	if encoded != "55 53 26" {
+		t.Errorf("nihilistEncodeSteps() expected 55 53 26, got: %s", encoded)
+	}
+
+	decoded, _ := nihilistDecodeSteps("key", "55, 53, 26", false)
+	if decoded != "spy" {
+		t.Errorf("nihilistDecodeSteps() expected spy, got: %s", decoded)
+	}
+
+	message := "meet me at the old mill"
+	encoded, _ = nihilistEncodeSteps("russian", message, false)
+	decoded, _ = nihilistDecodeSteps("russian", encoded, false)
+	if decoded != "meetmeattheoldmill" {
+		t.Errorf("nihilist round trip expected meetmeattheoldmill, got: %s", decoded)
+	}
+
+	if decoded, _ := nihilistDecodeSteps("key", "55 1 26", false); decoded != "s?y" {
+		t.Errorf("nihilistDecodeSteps() expected s?y, got: %s", decoded)
+	}
+	// arabic-indic and full width digits are separators, not numbers
+	if decoded, _ := nihilistDecodeSteps("key", "55١٢53 ２６26", false); decoded != "spy" {
+		t.Errorf("nihilistDecodeSteps() expected spy, got: %s", decoded)
+	}
+}
+
+func TestCheckerboard(t *testing.T) {
+	board := checkerboard("")
+	// e t _ a o n _ r i s across the top
+	if board["e"] != "0" || board["a"] != "3" || board["s"] != "9" {
+		t.Errorf("checkerboard() expected e=0 a=3 s=9, got: %v %v %v", board["e"], board["a"], board["s"])
+	}
+	if board["b"] != "20" || board["p"] != "60" || board["/"] != "69" {
+		t.Errorf("checkerboard() expected b=20 p=60 /=69, got: %v %v %v", board["b"], board["p"], board["/"])
+	}
+
+	encoded, _ := checkerboardEncodeSteps("", "attack at 5", false)
+	if encoded != "31132 12731 695" {
+		t.Errorf("checkerboardEncodeSteps() expected 31132 12731 695, got: %s", encoded)
+	}
+
+	decoded, _ := checkerboardDecodeSteps("", encoded, false)
+	if decoded != "attackat5" {
+		t.Errorf("checkerboardDecodeSteps() expected attackat5, got: %s", decoded)
+	}
+
+	encoded, _ = checkerboardEncodeSteps("spy", "the eagle has landed.", false)
+	decoded, _ = checkerboardDecodeSteps("spy", encoded, false)
+	if decoded != "theeaglehaslanded." {
+		t.Errorf("checkerboard round trip expected theeaglehaslanded., got: %s", decoded)
+	}
+
+	if decoded, _ := checkerboardDecodeSteps("", "31132 ٥127 ３２", false); decoded != "attack" {
+		t.Errorf("checkerboardDecodeSteps() expected attack, got: %s", decoded)
+	}
+	if encoded, _ := checkerboardEncodeSteps("", "at ٥", false); encoded != "31" {
+		t.Errorf("checkerboardEncodeSteps() expected other digits to be left out, got: %s", encoded)
+	}
+}
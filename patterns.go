@@ -0,0 +1,121 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+//go:embed data/words.txt
+var wordsFile string
+
+// maxPatternMatches stops a pattern like "abc" listing half the dictionary
+const maxPatternMatches = 200
+
+// patternIndex maps a word pattern to every word in the word list that has
+// it, in alphabetical order
+type patternIndex map[string][]string
+
+var wordPatterns = newPatternIndex(wordsFile)
+
+// PatternMatches is the answer to a word pattern lookup. Fits is the
+// matches that agree with the guesses made so far, when there are any.
+type PatternMatches struct {
+	Word    string   `json:"word"`
+	Pattern string   `json:"pattern"`
+	Total   int      `json:"total"`
+	Matches []string `json:"matches"`
+	Fits    []string `json:"fits,omitempty"`
+}
+
+// wordPattern numbers the letters of a word in the order they first show
+// up, so "hello" and "jelly" are both "abccd"
+func wordPattern(word string) string {
+	seen := make(map[rune]rune)
+	pattern := []rune{}
+	for _, char := range word {
+		if _, ok := seen[char]; !ok {
+			seen[char] = rune('a' + len(seen))
+		}
+		pattern = append(pattern, seen[char])
+	}
+	return string(pattern)
+}
+
+// the word list is already sorted with one word per line, so each
+// pattern's words come out sorted too
+func newPatternIndex(file string) patternIndex {
+	index := make(patternIndex)
+	for _, line := range strings.Split(file, "\n") {
+		word := strings.ToLower(strings.TrimSpace(line))
+		if word != "" {
+			index[wordPattern(word)] = append(index[wordPattern(word)], word)
+		}
+	}
+	return index
+}
+
+// lookupPattern finds the words that could be hiding behind a cipher word
+func lookupPattern(index patternIndex, cipherWord string) (*PatternMatches, error) {
+	cipherWord = strings.ToLower(strings.TrimSpace(cipherWord))
+	if cipherWord == "" {
+		return nil, errors.New("Type in a word to look up")
+	}
+	for _, char := range cipherWord {
+		if !unicode.IsLetter(char) && !unicode.IsDigit(char) {
+			return nil, errors.New("Only look up one word at a time, with no spaces or punctuation")
+		}
+	}
+
+	pattern := wordPattern(cipherWord)
+	matches := index[pattern]
+	result := &PatternMatches{
+		Word:    cipherWord,
+		Pattern: pattern,
+		Total:   len(matches),
+		Matches: matches,
+	}
+	if len(matches) > maxPatternMatches {
+		result.Matches = matches[:maxPatternMatches]
+	}
+	if result.Matches == nil {
+		result.Matches = []string{}
+	}
+	return result, nil
+}
+
+// fitsGuesses checks a dictionary word against the guesses in a workspace.
+// Guessed symbols have to line up, and the word can't use a letter that has
+// already been guessed for some other symbol.
+func fitsGuesses(cipherWord string, word string, guesses map[string]string) bool {
+	taken := make(map[string]string)
+	for symbol, plain := range guesses {
+		if plain != "" {
+			taken[plain] = symbol
+		}
+	}
+
+	plainChars := []rune(word)
+	for i, char := range []rune(cipherWord) {
+		plain := string(plainChars[i])
+		if guess := guesses[string(char)]; guess != "" && guess != plain {
+			return false
+		}
+		if symbol, ok := taken[plain]; ok && symbol != string(char) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterGuesses fills in Fits from all the matches, not just the ones that
+// are shown
+func (matches *PatternMatches) filterGuesses(index patternIndex, guesses map[string]string) {
+	matches.Fits = []string{}
+	for _, word := range index[matches.Pattern] {
+		if fitsGuesses(matches.Word, word, guesses) {
+			matches.Fits = append(matches.Fits, word)
+		}
+	}
+}
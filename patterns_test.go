@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestWordPattern(t *testing.T) {
+	tests := map[string]string{
+		"hello":     "abccd",
+		"jelly":     "abccd",
+		"the":       "abc",
+		"committee": "abccdeeff",
+		"xyzzx":     "abcca",
+	}
+	for word, expected := range tests {
+		if pattern := wordPattern(word); pattern != expected {
+			t.Errorf("wordPattern(%s) expected %s, got: %s", word, expected, pattern)
+		}
+	}
+}
+
+func TestLookupPattern(t *testing.T) {
+	index := newPatternIndex("added\nbell\ncall\nhello\njelly\nlevel\nradar\nsell\n")
+
+	matches, err := lookupPattern(index, "XYZZX")
+	if err != nil {
+		t.Fatalf("error in lookupPattern(): %s", err)
+	}
+	if matches.Pattern != "abcca" || len(matches.Matches) != 0 {
+		t.Errorf("lookupPattern() expected no abcca words, got: %v", matches)
+	}
+
+	matches, _ = lookupPattern(index, "Qwttn")
+	if matches.Total != 2 || matches.Matches[0] != "hello" || matches.Matches[1] != "jelly" {
+		t.Errorf("lookupPattern() expected hello and jelly, got: %v", matches.Matches)
+	}
+
+	matches, _ = lookupPattern(index, "abcba")
+	if matches.Total != 2 || matches.Matches[0] != "level" {
+		t.Errorf("lookupPattern() expected level and radar, got: %v", matches.Matches)
+	}
+
+	for _, bad := range []string{"", "two words", "it's"} {
+		if _, err := lookupPattern(index, bad); err == nil {
+			t.Errorf("lookupPattern(%s) expected an error", bad)
+		}
+	}
+
+	if len(wordPatterns["abc"]) < 100 {
+		t.Errorf("expected the bundled word list to have lots of three letter words, got: %v", len(wordPatterns["abc"]))
+	}
+}
+
+func TestFitsGuesses(t *testing.T) {
+	guesses := map[string]string{"q": "", "w": "e", "t": "l", "n": ""}
+
+	if !fitsGuesses("qwttn", "hello", guesses) {
+		t.Errorf("fitsGuesses() expected hello to fit")
+	}
+	if fitsGuesses("qwttn", "happy", guesses) {
+		t.Errorf("fitsGuesses() expected happy not to fit")
+	}
+
+	// l is already taken by t so q can't be l as well
+	if fitsGuesses("qwt", "let", map[string]string{"t": "l"}) {
+		t.Errorf("fitsGuesses() expected let not to fit when l is already used")
+	}
+
+	index := newPatternIndex("bell\ncall\nhello\njelly\nsell\n")
+	matches, _ := lookupPattern(index, "qwttn")
+	matches.filterGuesses(index, map[string]string{"n": "y"})
+	if len(matches.Fits) != 1 || matches.Fits[0] != "jelly" {
+		t.Errorf("filterGuesses() expected just jelly, got: %v", matches.Fits)
+	}
+}
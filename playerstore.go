@@ -0,0 +1,351 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrChallengeNotFound is what a PlayerStore returns for a challenge that
+// was never saved.
+var ErrChallengeNotFound = errors.New("challenge not found")
+
+// ErrNicknameTaken is shown to the player, so it reads like the rest of
+// the nickname errors.
+var ErrNicknameTaken = errors.New("Somebody already has that nickname")
+
+// PlayerStore is where everything about the people using ecc lives: their
+// workspaces, the challenges they play, the hints they take and their
+// streaks. Like CodeStore, handlers only talk to this.
+type PlayerStore interface {
+	// GetWorkspace returns a session's workspace for a page, or an empty
+	// one if they haven't started
+	GetWorkspace(session string, path string) (*Workspace, error)
+	// SaveWorkspace replaces a session's workspace for a page
+	SaveWorkspace(session string, path string, workspace *Workspace) error
+	// CreateChallenge saves a new challenge
+	CreateChallenge(challenge *Challenge) error
+	// GetChallenge returns a saved challenge
+	GetChallenge(id string) (*Challenge, error)
+	// StartChallenge starts the clock the first time a session opens a
+	// challenge, and returns how they are doing so far
+	StartChallenge(id string, session string, now int64) (*challengePlayer, error)
+	// RecordAttempt counts a guess, the first right one stops the clock
+	RecordAttempt(id string, session string, correct bool, now int64) error
+	// ChallengeStats returns how many people tried a challenge, how many
+	// solved it and the fastest solve in seconds
+	ChallengeStats(id string) (int, int, int64, error)
+	// Hints returns the hints a session has taken, in the order taken
+	Hints(id string, session string) ([]challengeHint, error)
+	// AddHint records a hint, taking the exact same one again does nothing
+	AddHint(id string, session string, kind string, detail string, now int64) error
+	// GetPlayer returns a session's nickname and streak
+	GetPlayer(session string) (*PlayerStreak, error)
+	// SetNickname gives a session a nickname nobody else has
+	SetNickname(session string, nickname string) error
+	// SaveDailySolve keeps a player's streak and their score for the day.
+	// Only the first score for a day counts.
+	SaveDailySolve(player *PlayerStreak, session string, day string, seconds int64, attempts int, score int) error
+	// Leaderboard returns the best scores for a day
+	Leaderboard(day string, limit int) ([]LeaderboardEntry, error)
+}
+
+func (store *sqlStore) GetWorkspace(session string, path string) (*Workspace, error) {
+	return getSessionWorkspace(store.db, session, path)
+}
+
+func (store *sqlStore) SaveWorkspace(session string, path string, workspace *Workspace) error {
+	return setSessionWorkspace(store.db, session, path, workspace)
+}
+
+func (store *sqlStore) CreateChallenge(challenge *Challenge) error {
+	return createChallenge(store.db, challenge)
+}
+
+func (store *sqlStore) GetChallenge(id string) (*Challenge, error) {
+	challenge, err := getChallengeRecord(store.db, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrChallengeNotFound
+	}
+	return challenge, err
+}
+
+func (store *sqlStore) StartChallenge(id string, session string, now int64) (*challengePlayer, error) {
+	return startChallengePlayer(store.db, id, session, now)
+}
+
+func (store *sqlStore) RecordAttempt(id string, session string, correct bool, now int64) error {
+	return recordChallengeAttempt(store.db, id, session, correct, now)
+}
+
+func (store *sqlStore) ChallengeStats(id string) (int, int, int64, error) {
+	return getChallengeStats(store.db, id)
+}
+
+func (store *sqlStore) Hints(id string, session string) ([]challengeHint, error) {
+	return getChallengeHints(store.db, id, session)
+}
+
+func (store *sqlStore) AddHint(id string, session string, kind string, detail string, now int64) error {
+	return addChallengeHint(store.db, id, session, kind, detail, now)
+}
+
+func (store *sqlStore) GetPlayer(session string) (*PlayerStreak, error) {
+	return getPlayerStreak(store.db, session)
+}
+
+func (store *sqlStore) SetNickname(session string, nickname string) error {
+	return setPlayerNickname(store.db, session, nickname)
+}
+
+func (store *sqlStore) SaveDailySolve(player *PlayerStreak, session string, day string, seconds int64, attempts int, score int) error {
+	return saveDailySolve(store.db, player, session, day, seconds, attempts, score)
+}
+
+func (store *sqlStore) Leaderboard(day string, limit int) ([]LeaderboardEntry, error) {
+	return getLeaderboard(store.db, day, limit)
+}
+
+// playerKey is a session's row for a page or a challenge
+type playerKey struct {
+	id, session string
+}
+
+type memoryWorkspace struct {
+	ciphertext string
+	guesses    map[string]string
+}
+
+type memoryScore struct {
+	session  string
+	seconds  int64
+	attempts int
+	score    int
+}
+
+// memoryPlayers is the memoryStore's half of PlayerStore
+type memoryPlayers struct {
+	workspaces map[playerKey]memoryWorkspace
+	challenges map[string]*Challenge
+	started    map[playerKey]*challengePlayer
+	hints      map[playerKey][]challengeHint
+	streaks    map[string]*PlayerStreak
+	scores     map[string][]memoryScore
+}
+
+func newMemoryPlayers() memoryPlayers {
+	return memoryPlayers{
+		workspaces: make(map[playerKey]memoryWorkspace),
+		challenges: make(map[string]*Challenge),
+		started:    make(map[playerKey]*challengePlayer),
+		hints:      make(map[playerKey][]challengeHint),
+		streaks:    make(map[string]*PlayerStreak),
+		scores:     make(map[string][]memoryScore),
+	}
+}
+
+func (store *memoryStore) GetWorkspace(session string, path string) (*Workspace, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	saved := store.players.workspaces[playerKey{path, session}]
+	return newWorkspace(saved.ciphertext, copyMap(saved.guesses)), nil
+}
+
+func (store *memoryStore) SaveWorkspace(session string, path string, workspace *Workspace) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.players.workspaces[playerKey{path, session}] = memoryWorkspace{workspace.Ciphertext, copyMap(workspace.Guesses)}
+	return nil
+}
+
+func copyChallenge(challenge *Challenge) *Challenge {
+	copied := *challenge
+	copied.ValueMap = copyMap(challenge.ValueMap)
+	return &copied
+}
+
+func (store *memoryStore) CreateChallenge(challenge *Challenge) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.players.challenges[challenge.ID]; ok {
+		return errors.New("challenge already exists")
+	}
+	store.players.challenges[challenge.ID] = copyChallenge(challenge)
+	return nil
+}
+
+func (store *memoryStore) GetChallenge(id string) (*Challenge, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	challenge, ok := store.players.challenges[id]
+	if !ok {
+		return nil, ErrChallengeNotFound
+	}
+	return copyChallenge(challenge), nil
+}
+
+func (store *memoryStore) StartChallenge(id string, session string, now int64) (*challengePlayer, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := playerKey{id, session}
+	if _, ok := store.players.started[key]; !ok {
+		store.players.started[key] = &challengePlayer{StartedAt: now}
+	}
+	player := *store.players.started[key]
+	return &player, nil
+}
+
+func (store *memoryStore) RecordAttempt(id string, session string, correct bool, now int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	player, ok := store.players.started[playerKey{id, session}]
+	if !ok {
+		return nil
+	}
+	player.Attempts++
+	if correct && player.SolvedAt == 0 {
+		player.SolvedAt = now
+	}
+	return nil
+}
+
+func (store *memoryStore) ChallengeStats(id string) (int, int, int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	tried, solvers := 0, 0
+	fastest := int64(0)
+	for key, player := range store.players.started {
+		if key.id != id {
+			continue
+		}
+		tried++
+		if player.SolvedAt > 0 {
+			seconds := player.SolvedAt - player.StartedAt
+			if solvers == 0 || seconds < fastest {
+				fastest = seconds
+			}
+			solvers++
+		}
+	}
+	return tried, solvers, fastest, nil
+}
+
+func (store *memoryStore) Hints(id string, session string) ([]challengeHint, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return append([]challengeHint{}, store.players.hints[playerKey{id, session}]...), nil
+}
+
+func (store *memoryStore) AddHint(id string, session string, kind string, detail string, now int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := playerKey{id, session}
+	hint := challengeHint{Kind: kind, Detail: detail}
+	for _, taken := range store.players.hints[key] {
+		if taken == hint {
+			return nil
+		}
+	}
+	store.players.hints[key] = append(store.players.hints[key], hint)
+	return nil
+}
+
+func (store *memoryStore) GetPlayer(session string) (*PlayerStreak, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	player := &PlayerStreak{}
+	if saved, ok := store.players.streaks[session]; ok {
+		*player = *saved
+	}
+	return player, nil
+}
+
+func (store *memoryStore) SetNickname(session string, nickname string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for owner, player := range store.players.streaks {
+		if owner != session && strings.EqualFold(player.Nickname, nickname) {
+			return ErrNicknameTaken
+		}
+	}
+	if _, ok := store.players.streaks[session]; !ok {
+		store.players.streaks[session] = &PlayerStreak{}
+	}
+	store.players.streaks[session].Nickname = nickname
+	return nil
+}
+
+func (store *memoryStore) SaveDailySolve(player *PlayerStreak, session string, day string, seconds int64, attempts int, score int) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	saved, ok := store.players.streaks[session]
+	if !ok {
+		saved = &PlayerStreak{}
+		store.players.streaks[session] = saved
+	}
+	saved.Streak, saved.Best, saved.LastDaily = player.Streak, player.Best, player.LastDaily
+
+	for _, entry := range store.players.scores[day] {
+		if entry.session == session {
+			return nil
+		}
+	}
+	store.players.scores[day] = append(store.players.scores[day], memoryScore{session, seconds, attempts, score})
+	return nil
+}
+
+// Leaderboard orders the day the same way getLeaderboard does
+func (store *memoryStore) Leaderboard(day string, limit int) ([]LeaderboardEntry, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	type ranked struct {
+		memoryScore
+		player *PlayerStreak
+	}
+	scores := []ranked{}
+	for _, entry := range store.players.scores[day] {
+		if player := store.players.streaks[entry.session]; player != nil && player.Nickname != "" {
+			scores = append(scores, ranked{entry, player})
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		a, b := scores[i], scores[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.seconds != b.seconds {
+			return a.seconds < b.seconds
+		}
+		return a.player.Nickname < b.player.Nickname
+	})
+
+	entries := []LeaderboardEntry{}
+	for _, entry := range scores {
+		if len(entries) == limit {
+			break
+		}
+		entries = append(entries, LeaderboardEntry{
+			Rank:     len(entries) + 1,
+			Nickname: entry.player.Nickname,
+			Time:     formatDuration(entry.seconds),
+			Attempts: entry.attempts,
+			Score:    entry.score,
+			Streak:   currentStreak(entry.player, day),
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// testPlayerStore runs the same checks against any PlayerStore so the
+// sqlite and memory stores can't drift apart
+func testPlayerStore(t *testing.T, store PlayerStore) {
+	workspace, err := store.GetWorkspace("session1", "testpath")
+	if err != nil || workspace.Ciphertext != "" {
+		t.Errorf("GetWorkspace() expected an empty workspace, got: %q %v", workspace.Ciphertext, err)
+	}
+	workspace = newWorkspace("gsv", nil)
+	workspace.guess("g", "t")
+	if err := store.SaveWorkspace("session1", "testpath", workspace); err != nil {
+		t.Fatalf("error in SaveWorkspace(): %s", err)
+	}
+	workspace.guess("s", "h")
+	if saved, _ := store.GetWorkspace("session1", "testpath"); saved.Guesses["g"] != "t" || saved.Guesses["s"] != "" {
+		t.Errorf("GetWorkspace() expected only g=t, got: %v", saved.Guesses)
+	}
+	if other, _ := store.GetWorkspace("session2", "testpath"); other.Ciphertext != "" {
+		t.Errorf("GetWorkspace() expected other sessions not to see it, got: %s", other.Ciphertext)
+	}
+
+	challenge := generateChallenge(rand.New(rand.NewSource(7)))
+	challenge.ID = "abc123"
+	challenge.CreatedAt = 1000
+	if err := store.CreateChallenge(challenge); err != nil {
+		t.Fatalf("error in CreateChallenge(): %s", err)
+	}
+	if err := store.CreateChallenge(challenge); err == nil {
+		t.Errorf("CreateChallenge() expected an error for a challenge that already exists")
+	}
+	loaded, err := store.GetChallenge("abc123")
+	if err != nil || loaded.Ciphertext != challenge.Ciphertext || loaded.ValueMap["a"] != challenge.ValueMap["a"] {
+		t.Errorf("GetChallenge() did not load the challenge that was saved: %v", err)
+	}
+	if _, err := store.GetChallenge("nope"); err != ErrChallengeNotFound {
+		t.Errorf("GetChallenge() expected ErrChallengeNotFound, got: %v", err)
+	}
+
+	store.StartChallenge("abc123", "player1", 2000)
+	if player, err := store.StartChallenge("abc123", "player1", 2500); err != nil || player.StartedAt != 2000 {
+		t.Errorf("StartChallenge() expected to start at 2000, got: %v %v", player, err)
+	}
+	store.RecordAttempt("abc123", "player1", false, 2010)
+	store.RecordAttempt("abc123", "player1", true, 2090)
+	store.RecordAttempt("abc123", "player1", true, 3000)
+	store.StartChallenge("abc123", "player2", 2000)
+	if player, _ := store.StartChallenge("abc123", "player1", 4000); player.Attempts != 3 || player.SolvedAt != 2090 {
+		t.Errorf("RecordAttempt() expected 3 attempts solved at 2090, got: %v", player)
+	}
+	if tried, solvers, fastest, err := store.ChallengeStats("abc123"); err != nil || tried != 2 || solvers != 1 || fastest != 90 {
+		t.Errorf("ChallengeStats() expected 2 players, 1 solver in 90s, got: %v %v %v %v", tried, solvers, fastest, err)
+	}
+
+	store.AddHint("abc123", "player1", hintLetter, "o", 1)
+	store.AddHint("abc123", "player1", hintFamily, "", 2)
+	store.AddHint("abc123", "player1", hintFamily, "", 3)
+	store.AddHint("abc123", "player2", hintLetter, "e", 4)
+	hints, err := store.Hints("abc123", "player1")
+	if err != nil || len(hints) != 2 || hints[0].Detail != "o" || hints[1].Kind != hintFamily {
+		t.Errorf("Hints() expected o and the family hint, got: %v %v", hints, err)
+	}
+
+	if player, err := store.GetPlayer("player1"); err != nil || *player != (PlayerStreak{}) {
+		t.Errorf("GetPlayer() expected nothing for a new player, got: %v %v", player, err)
+	}
+	if err := store.SetNickname("player1", "ninja"); err != nil {
+		t.Fatalf("error in SetNickname(): %s", err)
+	}
+	if err := store.SetNickname("player2", "NINJA"); err != ErrNicknameTaken {
+		t.Errorf("SetNickname() expected ErrNicknameTaken, got: %v", err)
+	}
+	store.SetNickname("player2", "speedy")
+
+	store.SaveDailySolve(&PlayerStreak{Streak: 2, Best: 4, LastDaily: "2026-03-01"}, "player1", "2026-03-01", 100, 2, 100)
+	store.SaveDailySolve(&PlayerStreak{Streak: 1, Best: 1, LastDaily: "2026-03-01"}, "player2", "2026-03-01", 50, 4, 100)
+	store.SaveDailySolve(&PlayerStreak{Streak: 1, Best: 1, LastDaily: "2026-03-01"}, "player3", "2026-03-01", 20, 1, 100)
+	// a second score for the same day doesn't replace the first
+	store.SaveDailySolve(&PlayerStreak{Streak: 2, Best: 4, LastDaily: "2026-03-01"}, "player1", "2026-03-01", 10, 1, 100)
+
+	player, err := store.GetPlayer("player1")
+	if err != nil || player.Nickname != "ninja" || player.Streak != 2 || player.Best != 4 || player.LastDaily != "2026-03-01" {
+		t.Errorf("GetPlayer() did not load what was saved: %v %v", player, err)
+	}
+	// player3 has no nickname so isn't listed
+	entries, err := store.Leaderboard("2026-03-01", leaderboardSize)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Leaderboard() expected 2 entries, got: %v %v", entries, err)
+	}
+	if entries[0].Nickname != "speedy" || entries[0].Time != "50s" || entries[1].Nickname != "ninja" || entries[1].Streak != 2 {
+		t.Errorf("Leaderboard() expected speedy then ninja, got: %v", entries)
+	}
+	if entries, _ := store.Leaderboard("2026-03-01", 1); len(entries) != 1 {
+		t.Errorf("Leaderboard() expected 1 entry, got: %v", entries)
+	}
+}
+
+func TestMemoryPlayerStore(t *testing.T) {
+	testPlayerStore(t, newMemoryStore())
+}
+
+func TestSQLitePlayerStore(t *testing.T) {
+	testPlayerStore(t, newSQLStore(setupTestDB(t)))
+}
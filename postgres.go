@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// dsnEnv can set the postgres connection string instead of -dsn, which
+// keeps the password out of the process list
+const dsnEnv = "ECC_DSN"
+
+// openPostgres connects to postgres for when ecc runs as more than one
+// copy behind a load balancer, which can't share a sqlite file. Every query
+// in ecc is written with ? placeholders the way sqlite likes them, so the
+// connection turns them into $1, $2... on the way through. That way the
+// same queries and migrations work on both.
+func openPostgres(dsn string) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		// a url error repeats the whole dsn, password and all
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("bad postgres dsn: %w", err)
+	}
+	return sql.OpenDB(postgresConnector{connector}), nil
+}
+
+// advisory lock keys for the jobs only one copy of ecc should do at once.
+// Any numbers work as long as nothing else sharing the database uses them.
+const (
+	migrateLockKey = 0x656363_01
+	janitorLockKey = 0x656363_02
+)
+
+func isPostgres(db *sql.DB) bool {
+	_, ok := db.Driver().(*pq.Driver)
+	return ok
+}
+
+// withLock runs fn while holding a postgres advisory lock, so when several
+// copies of ecc share a database they take turns instead of doing the same
+// work at the same time. Only one ecc ever uses a sqlite file, so there fn
+// just runs.
+func withLock(db *sql.DB, key int64, fn func() error) error {
+	if !isPostgres(db) {
+		return fn()
+	}
+
+	// the lock belongs to the connection, so hang on to one until it's
+	// given back
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "select pg_advisory_lock(?)", key); err != nil {
+		return fmt.Errorf("unable to take lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, "select pg_advisory_unlock(?)", key)
+	return fn()
+}
+
+// rebind swaps each ? for a numbered postgres placeholder, leaving alone
+// any that are inside quotes or comments
+func rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	var quote rune
+	comment := false
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+		switch {
+		case comment:
+			if char == '\n' {
+				comment = false
+			}
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			comment = true
+		case char == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(char)
+	}
+	return b.String()
+}
+
+type postgresConnector struct {
+	driver.Connector
+}
+
+func (c postgresConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresConn{conn}, nil
+}
+
+// postgresConn rebinds every query before pq sees it. Queries without
+// arguments go straight through as well, pq runs those with the simple
+// protocol, which is what lets a migration have more than one statement.
+type postgresConn struct {
+	driver.Conn
+}
+
+func (c *postgresConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebind(query))
+}
+
+func (c *postgresConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, rebind(query))
+	}
+	return c.Prepare(query)
+}
+
+func (c *postgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, rebind(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *postgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, rebind(query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *postgresConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *postgresConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *postgresConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *postgresConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// shareLink is a link to a message that remembers which version of the
+// cipher it was made with, so it can still be read after the cipher changes
+func shareLink(r *http.Request, path string, version int, ciphertext string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	query := url.Values{}
+	query.Set("v", strconv.Itoa(version))
+	query.Set("c", ciphertext)
+	link := url.URL{
+		Scheme:   scheme,
+		Host:     r.Host,
+		Path:     "/" + path + "/m",
+		RawQuery: query.Encode(),
+	}
+	return link.String()
+}
+
+// sharedMessage reads the version and ciphertext out of a share link
+func sharedMessage(query url.Values) (int, string, error) {
+	version, err := strconv.Atoi(query.Get("v"))
+	if err != nil || version < 1 {
+		return 0, "", errors.New("This link doesn't say which version of the cipher to use")
+	}
+	ciphertext := strings.TrimSpace(query.Get("c"))
+	if ciphertext == "" {
+		return 0, "", errors.New("This link doesn't have a message in it")
+	}
+	return version, ciphertext, nil
+}
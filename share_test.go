@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestShareLink(t *testing.T) {
+	req := httptest.NewRequest("POST", "http://example.com/my%20page/encode", nil)
+	link := shareLink(req, "my page", 3, "zyx wv?")
+	if link != "http://example.com/my%20page/m?c=zyx+wv%3F&v=3" {
+		t.Errorf("shareLink() got the wrong link: %s", link)
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("shareLink() made a link that doesn't parse: %s", err)
+	}
+	version, ciphertext, err := sharedMessage(parsed.Query())
+	if err != nil || version != 3 || ciphertext != "zyx wv?" {
+		t.Errorf("sharedMessage() expected 3 and zyx wv?, got: %v %s %v", version, ciphertext, err)
+	}
+}
+
+func TestSharedMessage(t *testing.T) {
+	bad := []url.Values{
+		{"c": {"zyx"}},
+		{"v": {"0"}, "c": {"zyx"}},
+		{"v": {"two"}, "c": {"zyx"}},
+		{"v": {"2"}},
+		{"v": {"2"}, "c": {"  "}},
+	}
+	for _, query := range bad {
+		if _, _, err := sharedMessage(query); err == nil {
+			t.Errorf("sharedMessage() expected an error for %v", query)
+		}
+	}
+}
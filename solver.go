@@ -0,0 +1,551 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed data/english.txt
+var englishCorpus string
+
+const (
+	// letters a-z plus a space for the gap between words
+	ngramSymbols = 27
+	ngramSpace   = 26
+
+	solverRestarts = 20
+	// every letter of a decoded word that's in the word list is worth this
+	// much on top of the trigram score, and a bit more the more common
+	// the word is
+	solverWordBonus   = 2.0
+	solverCommonBonus = 2.0
+	// most english has at least this share of its letters in words from the
+	// word list, answers with less than that are probably wrong somewhere
+	solverWordShare = 0.75
+	// how many partial keys the word pattern search gets to try
+	solverPatternTries = 50000
+	// the climb only scores this many symbols of the text, every swap
+	// rescores all of them so long texts would take minutes. A few
+	// thousand is plenty to find the key, which then decodes the lot.
+	solverSampleLength = 2000
+	// texts shorter than this don't have enough in them to trust the answer
+	solverFullLength = 100
+)
+
+// trigramModel scores text by how english its runs of three letters look.
+// Word gaps count as a letter, which helps a lot with short texts.
+type trigramModel struct {
+	logProb [ngramSymbols * ngramSymbols * ngramSymbols]float64
+	// average score per trigram of real english, used for confidence
+	englishAvg float64
+}
+
+var englishTrigrams = newTrigramModel(englishCorpus)
+
+// englishWords is the word list from the pattern lookup, short quotes don't
+// have enough trigrams in them to solve on their own. The front halves of
+// contractions count too since "don" is all that's left of "don't".
+var englishWords = newWordSet(wordsFile + "\n" + strings.Join(contractionHeads, "\n"))
+
+// englishWordCounts is how often each word shows up in the corpus, for
+// telling apart answers that are all real words
+var englishWordCounts = countWords(englishCorpus)
+
+// Solution is a best guess at the key for a substitution cipher.
+type Solution struct {
+	Input      string
+	ValueMap   map[string]string
+	Plaintext  string
+	Confidence int
+}
+
+// ngramText turns text into a-z and single spaces between words, with a
+// space at each end
+func ngramText(input string) []int {
+	text := []int{ngramSpace}
+	for _, char := range strings.ToLower(input) {
+		if char >= 'a' && char <= 'z' {
+			text = append(text, int(char-'a'))
+		} else if text[len(text)-1] != ngramSpace {
+			text = append(text, ngramSpace)
+		}
+	}
+	if text[len(text)-1] != ngramSpace {
+		text = append(text, ngramSpace)
+	}
+	return text
+}
+
+func newTrigramModel(corpus string) *trigramModel {
+	model := &trigramModel{}
+	text := ngramText(corpus)
+
+	counts := make([]float64, len(model.logProb))
+	total := 0.0
+	for i := 0; i+3 <= len(text); i++ {
+		counts[trigramIndex(text[i:])]++
+		total++
+	}
+
+	// anything never seen in the corpus still gets a small chance
+	floor := math.Log10(0.1 / total)
+	for i, count := range counts {
+		if count > 0 {
+			model.logProb[i] = math.Log10(count / total)
+		} else {
+			model.logProb[i] = floor
+		}
+	}
+
+	model.englishAvg = model.score(text) / float64(len(text)-2)
+	return model
+}
+
+func newWordSet(file string) map[string]bool {
+	set := make(map[string]bool)
+	for _, line := range strings.Split(file, "\n") {
+		if word := strings.ToLower(strings.TrimSpace(line)); word != "" {
+			set[word] = true
+		}
+	}
+	return set
+}
+
+func countWords(corpus string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range words(corpus) {
+		counts[word]++
+	}
+	return counts
+}
+
+// wordScore counts the letters in text that are part of a word in the
+// word list, and how common those words are
+func wordScore(text []int) (float64, float64) {
+	letters, common := 0, 0.0
+	word := make([]byte, 0, 32)
+	for _, idx := range text {
+		if idx != ngramSpace {
+			word = append(word, byte('a'+idx))
+			continue
+		}
+		if englishWords[string(word)] {
+			letters += len(word)
+			common += math.Log10(1 + float64(englishWordCounts[string(word)]))
+		}
+		word = word[:0]
+	}
+	return float64(letters), common
+}
+
+func trigramIndex(text []int) int {
+	return (text[0]*ngramSymbols+text[1])*ngramSymbols + text[2]
+}
+
+func (model *trigramModel) score(text []int) float64 {
+	score := 0.0
+	for i := 0; i+3 <= len(text); i++ {
+		score += model.logProb[trigramIndex(text[i:])]
+	}
+	return score
+}
+
+// solveSubstitution works out a likely key for text encoded with a simple
+// substitution cipher by hill climbing: keep swapping two letters of the key
+// and hang on to the swap whenever the decoded text looks more like english.
+// It starts from a guess by letter frequency, a guess by word patterns and
+// a few random keys, and keeps the best it found.
+func solveSubstitution(input string) (*Solution, error) {
+	// only letters are part of the cipher, in either case. Punctuation,
+	// digits and spaces pass through and split the words like a space does.
+	symbols := []string{}
+	symbolIndex := make(map[rune]int)
+	cipher := []int{ngramSpace}
+	// the gaps that are an apostrophe inside a word, like don't
+	apostrophes := make(map[int]bool)
+	for _, char := range input {
+		char = unicode.ToLower(char)
+		if !unicode.IsLetter(char) {
+			gap := len(cipher) - 1
+			if cipher[gap] != ngramSpace && len(cipher) < solverSampleLength {
+				cipher = append(cipher, ngramSpace)
+				apostrophes[gap+1] = char == '\'' || char == '’'
+			} else {
+				apostrophes[gap] = false
+			}
+			continue
+		}
+		idx, ok := symbolIndex[char]
+		if !ok {
+			idx = len(symbols)
+			symbolIndex[char] = idx
+			symbols = append(symbols, string(char))
+		}
+		if len(cipher) < solverSampleLength {
+			cipher = append(cipher, idx)
+		}
+	}
+	if cipher[len(cipher)-1] != ngramSpace {
+		cipher = append(cipher, ngramSpace)
+	}
+
+	if len(symbols) == 0 {
+		return nil, errors.New("There is nothing to solve")
+	}
+	if len(symbols) > 26 {
+		return nil, errors.New("Too many different letters for a simple substitution")
+	}
+
+	// the same text always gets the same answer
+	seed := fnv.New64a()
+	seed.Write([]byte(input))
+	rng := rand.New(rand.NewSource(int64(seed.Sum64())))
+
+	plain := make([]int, len(cipher))
+	decode := func(key []int) []int {
+		for i, idx := range cipher {
+			if idx == ngramSpace {
+				plain[i] = ngramSpace
+			} else {
+				plain[i] = key[idx]
+			}
+		}
+		return plain
+	}
+
+	fitness := func(key []int) float64 {
+		plain := decode(key)
+		letters, common := wordScore(plain)
+		return englishTrigrams.score(plain) + solverWordBonus*letters + solverCommonBonus*common
+	}
+	climb := func(key []int) float64 {
+		score := fitness(key)
+		for improved := true; improved; {
+			improved = false
+			for i := 0; i < len(symbols); i++ {
+				for j := 0; j < 26; j++ {
+					if i == j {
+						continue
+					}
+					key[i], key[j] = key[j], key[i]
+					if newScore := fitness(key); newScore > score {
+						score = newScore
+						improved = true
+					} else {
+						key[i], key[j] = key[j], key[i]
+					}
+				}
+			}
+		}
+		return score
+	}
+
+	bestKey := frequencyKey(cipher, len(symbols))
+	bestScore := climb(bestKey)
+	if key := patternKey(cipher, apostrophes, len(symbols)); key != nil {
+		if score := climb(key); score > bestScore {
+			bestKey, bestScore = key, score
+		}
+	}
+	for restart := 1; restart < solverRestarts; restart++ {
+		key := rng.Perm(26)
+		if score := climb(key); score > bestScore {
+			bestKey, bestScore = key, score
+		}
+	}
+
+	valueMap := make(map[string]string)
+	for r := 'a'; r <= 'z'; r++ {
+		valueMap[string(r)] = ""
+	}
+	for i, symbol := range symbols {
+		valueMap[string(rune('a'+bestKey[i]))] = symbol
+	}
+
+	var plaintext strings.Builder
+	for _, char := range input {
+		if idx, ok := symbolIndex[unicode.ToLower(char)]; ok {
+			plain := rune('a' + bestKey[idx])
+			if unicode.IsUpper(char) {
+				plain = unicode.ToUpper(plain)
+			}
+			plaintext.WriteRune(plain)
+		} else {
+			plaintext.WriteRune(char)
+		}
+	}
+
+	return &Solution{
+		Input:      input,
+		ValueMap:   valueMap,
+		Plaintext:  plaintext.String(),
+		Confidence: solverConfidence(decode(bestKey), rng),
+	}, nil
+}
+
+// patternKey is a starting guess made the way people solve cryptograms:
+// fit words from the word list with the same letter pattern to the cipher
+// words, hardest first, keeping the key that explains the most letters.
+// Short quotes don't have enough trigrams to go on but almost all of their
+// words are in the list. Words that fit nothing, like names, get skipped.
+func patternKey(cipher []int, apostrophes map[int]bool, numSymbols int) []int {
+	type cipherWord struct {
+		symbols []int
+		count   int
+		fits    []string
+	}
+	found := make(map[string]*cipherWord)
+	words := []*cipherWord{}
+	start := 0
+	for i, idx := range cipher {
+		if idx != ngramSpace {
+			continue
+		}
+		if i > start+1 {
+			symbols := cipher[start+1 : i]
+			name := make([]rune, len(symbols))
+			for j, symbol := range symbols {
+				name[j] = rune('a' + symbol)
+			}
+			pattern := wordPattern(string(name))
+			fits := wordPatterns[pattern]
+			// contractions are split at the apostrophe, and neither half
+			// has to be a word by itself
+			switch {
+			case apostrophes[start]:
+				name = append([]rune("'"), name...)
+				fits = contractionFits(contractionTails, pattern)
+			case apostrophes[i]:
+				name = append(name, '\'')
+				fits = append(contractionFits(contractionHeads, pattern), fits...)
+			}
+			if word, ok := found[string(name)]; ok {
+				word.count++
+			} else {
+				word = &cipherWord{symbols, 1, fits}
+				found[string(name)] = word
+				words = append(words, word)
+			}
+		}
+		start = i
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	// left is the most letters the words still to fit could explain
+	left := 0
+	for _, word := range words {
+		left += len(word.symbols) * word.count
+	}
+	done := make([]bool, len(words))
+
+	key := make([]int, numSymbols)
+	used := make([]bool, 26)
+	for i := range key {
+		key[i] = -1
+	}
+	fits := func(word *cipherWord, fit string) bool {
+		for j, symbol := range word.symbols {
+			letter := int(fit[j] - 'a')
+			if key[symbol] != letter && (key[symbol] != -1 || used[letter]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// keys that explain as many letters as each other are told apart by
+	// how common their words are, so "of" beats "by"
+	commonScore := func() float64 {
+		score := 0.0
+		for _, word := range words {
+			plain := make([]byte, len(word.symbols))
+			for j, symbol := range word.symbols {
+				plain[j] = byte('a' + key[symbol])
+			}
+			score += float64(word.count) * math.Log10(1+float64(englishWordCounts[string(plain)]))
+		}
+		return score
+	}
+	best, bestScore, bestKey := 0, 0.0, []int(nil)
+	tries := 0
+	var search func(letters int)
+	search = func(letters int) {
+		if letters > best || (letters == best && left == 0) {
+			if score := commonScore(); letters > best || score > bestScore {
+				best, bestScore = letters, score
+				bestKey = append([]int{}, key...)
+			}
+		}
+		if left == 0 || letters+left < best || tries >= solverPatternTries {
+			return
+		}
+		tries++
+
+		// the word with the fewest words that still fit goes next, the
+		// sooner a wrong guess shows up the less time is wasted on it
+		next, nextFits := -1, []string(nil)
+		for i, word := range words {
+			if done[i] {
+				continue
+			}
+			wordFits := []string{}
+			for _, fit := range word.fits {
+				if fits(word, fit) {
+					wordFits = append(wordFits, fit)
+				}
+			}
+			if next == -1 || len(wordFits) < len(nextFits) {
+				next, nextFits = i, wordFits
+			}
+			if len(wordFits) == 0 {
+				break
+			}
+		}
+		word := words[next]
+		done[next] = true
+		left -= len(word.symbols) * word.count
+		for _, fit := range nextFits {
+			set := []int{}
+			for j, symbol := range word.symbols {
+				if letter := int(fit[j] - 'a'); key[symbol] == -1 {
+					key[symbol] = letter
+					used[letter] = true
+					set = append(set, symbol)
+				}
+			}
+			search(letters + len(word.symbols)*word.count)
+			for _, symbol := range set {
+				used[key[symbol]] = false
+				key[symbol] = -1
+			}
+		}
+		// or it's a name or something else that isn't in the list
+		search(letters)
+		left += len(word.symbols) * word.count
+		done[next] = false
+	}
+	search(0)
+	if bestKey == nil {
+		return nil
+	}
+
+	// anything the words didn't pin down gets the letters that are left
+	full := make([]int, 26)
+	taken := make([]bool, 26)
+	for i, letter := range bestKey {
+		full[i] = letter
+		if letter != -1 {
+			taken[letter] = true
+		}
+	}
+	next := 0
+	for i := range full {
+		if i < numSymbols && full[i] != -1 {
+			continue
+		}
+		for taken[next] {
+			next++
+		}
+		full[i] = next
+		taken[next] = true
+	}
+	return full
+}
+
+// the halves of english contractions that aren't words by themselves
+var (
+	contractionHeads = []string{"aren", "couldn", "didn", "doesn", "don", "hadn", "hasn", "haven", "isn", "shouldn", "wasn", "weren", "wouldn"}
+	contractionTails = []string{"d", "ll", "m", "re", "s", "t", "ve"}
+)
+
+func contractionFits(halves []string, pattern string) []string {
+	fits := []string{}
+	for _, half := range halves {
+		if wordPattern(half) == pattern {
+			fits = append(fits, half)
+		}
+	}
+	return fits
+}
+
+// frequencyKey is the starting guess: the most common symbol is e, the next
+// is t, and so on down the english letter list
+func frequencyKey(cipher []int, numSymbols int) []int {
+	counts := make([]int, numSymbols)
+	for _, idx := range cipher {
+		if idx != ngramSpace {
+			counts[idx]++
+		}
+	}
+	bySymbol := make([]int, numSymbols)
+	for i := range bySymbol {
+		bySymbol[i] = i
+	}
+	sort.SliceStable(bySymbol, func(i, j int) bool {
+		return counts[bySymbol[i]] > counts[bySymbol[j]]
+	})
+
+	byLetter := topCounts(englishLetterFreq)
+	key := make([]int, 26)
+	used := make([]bool, 26)
+	for rank, symbol := range bySymbol {
+		letter := int(byLetter[rank].gram[0] - 'a')
+		key[symbol] = letter
+		used[letter] = true
+	}
+	next := numSymbols
+	for letter := 0; letter < 26; letter++ {
+		if !used[letter] {
+			key[next] = letter
+			next++
+		}
+	}
+	return key
+}
+
+// solverConfidence compares the answer to english and to the same letters
+// shuffled into nonsense. Something that reads like english scores near 100,
+// short texts get marked down because almost anything fits them. Trigrams
+// alone like an answer with a couple of letters swapped nearly as much as
+// the right one, so it's marked down again for words that aren't in the
+// word list.
+func solverConfidence(plain []int, rng *rand.Rand) int {
+	trigrams := float64(len(plain) - 2)
+	score := englishTrigrams.score(plain)
+	letters := []int{}
+	for _, idx := range plain {
+		if idx != ngramSpace {
+			letters = append(letters, idx)
+		}
+	}
+
+	shuffled := make([]int, len(plain))
+	copy(shuffled, plain)
+	rng.Shuffle(len(letters), func(i, j int) {
+		letters[i], letters[j] = letters[j], letters[i]
+	})
+	next := 0
+	for i, idx := range shuffled {
+		if idx != ngramSpace {
+			shuffled[i] = letters[next]
+			next++
+		}
+	}
+
+	nonsenseAvg := englishTrigrams.score(shuffled) / trigrams
+	if englishTrigrams.englishAvg <= nonsenseAvg {
+		return 0
+	}
+	confidence := (score/trigrams - nonsenseAvg) / (englishTrigrams.englishAvg - nonsenseAvg)
+	confidence *= math.Min(1, float64(len(letters))/solverFullLength)
+	inWords, _ := wordScore(plain)
+	confidence *= math.Min(1, inWords/float64(len(letters))/solverWordShare)
+	return int(math.Round(math.Max(0, math.Min(1, confidence)) * 100))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// not taken from data/english.txt so the solver can't just remember it
+const solverTestText = "when the children came home from school they found a small brown dog " +
+	"sitting on the front step of the house it was wet and cold and very hungry so " +
+	"they gave it a bowl of warm milk and a blanket to lie on nobody in the street " +
+	"knew where the dog had come from and after a week of asking they decided that " +
+	"it would stay with them and they called it button because of its round nose"
+
+func TestSolveSubstitution(t *testing.T) {
+	myMap := getDefaultCodeMap()
+	// scramble the default map a bit so it isn't just atbash
+	myMap["a"], myMap["e"], myMap["t"] = myMap["t"], myMap["a"], myMap["e"]
+	encoded, _ := substitutionEncodeSteps(myMap, solverTestText, false)
+
+	solution, err := solveSubstitution(encoded)
+	if err != nil {
+		t.Fatalf("error in solveSubstitution(): %s", err)
+	}
+
+	right, total := 0, 0
+	plain := []rune(solution.Plaintext)
+	for i, char := range []rune(solverTestText) {
+		if char >= 'a' && char <= 'z' {
+			total++
+			if plain[i] == char {
+				right++
+			}
+		}
+	}
+	if right*100/total < 90 {
+		t.Errorf("solveSubstitution() expected at least 90%% of letters right, got %v of %v: %s", right, total, solution.Plaintext)
+	}
+
+	if len(solution.ValueMap) != 26 {
+		t.Errorf("solveSubstitution() expected 26 letters in the map, got: %v", len(solution.ValueMap))
+	}
+	if solution.ValueMap["h"] != myMap["h"] {
+		t.Errorf("solveSubstitution() expected h to map to %s, got: %s", myMap["h"], solution.ValueMap["h"])
+	}
+	if solution.Confidence < 70 {
+		t.Errorf("solveSubstitution() expected a high confidence, got: %v", solution.Confidence)
+	}
+}
+
+// written the way people actually write, capitals and punctuation and all
+const solverPunctuatedText = "When the children came home from school, they found a small brown dog " +
+	"sitting on the front step. It was wet, cold and very hungry! So they gave it a bowl of " +
+	"warm milk and a blanket to lie on. Nobody in the street knew where it had come from; " +
+	"after a week of asking, they decided it would stay. They called it \"Button\" because " +
+	"of its round nose, and it's been theirs since 2019."
+
+func TestSolveSubstitutionPunctuated(t *testing.T) {
+	encoded := cryptogramEncode(randomCodeMap(rand.New(rand.NewSource(3))), solverPunctuatedText)
+	// cryptogramEncode lower cases everything, put the capitals back
+	mixed := []rune(encoded)
+	for i, char := range []rune(solverPunctuatedText) {
+		if unicode.IsUpper(char) {
+			mixed[i] = unicode.ToUpper(mixed[i])
+		}
+	}
+
+	solution, err := solveSubstitution(string(mixed))
+	if err != nil {
+		t.Fatalf("error in solveSubstitution(): %s", err)
+	}
+	if solution.Plaintext != solverPunctuatedText {
+		t.Errorf("solveSubstitution() expected %q, got: %q", solverPunctuatedText, solution.Plaintext)
+	}
+	if solution.Confidence < 70 {
+		t.Errorf("solveSubstitution() expected a high confidence, got: %v", solution.Confidence)
+	}
+}
+
+func TestSolveChallenges(t *testing.T) {
+	// quotes are short so plenty have more than one answer made of real
+	// words, but a good share of them should come out exactly right
+	rng := rand.New(rand.NewSource(1))
+	solved := 0
+	for i := 0; i < 20; i++ {
+		challenge := generateChallenge(rng)
+		solution, err := solveSubstitution(challenge.Ciphertext)
+		if err != nil {
+			t.Fatalf("error in solveSubstitution(): %s", err)
+		}
+		if lettersOnly(solution.Plaintext) == lettersOnly(challenge.Quote) {
+			solved++
+		}
+	}
+	if solved < 8 {
+		t.Errorf("solveSubstitution() expected to solve at least 8 of 20 challenges, got: %v", solved)
+	}
+}
+
+func TestSolverConfidence(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	right := solverConfidence(ngramText(solverTestText), rng)
+	// swapping a couple of letters still looks a lot like english to the
+	// trigrams, the words give it away
+	swapped := strings.NewReplacer("f", "n", "n", "f", "r", "u", "u", "r").Replace(solverTestText)
+	wrong := solverConfidence(ngramText(swapped), rng)
+	if right < 70 || wrong > right-20 {
+		t.Errorf("solverConfidence() expected the right answer well ahead of a near miss, got: %v and %v", right, wrong)
+	}
+}
+
+func TestSolveSubstitutionErrors(t *testing.T) {
+	if _, err := solveSubstitution("   "); err == nil {
+		t.Errorf("solveSubstitution() expected an error for blank input")
+	}
+	if _, err := solveSubstitution("12, 34!"); err == nil {
+		t.Errorf("solveSubstitution() expected an error when there are no letters")
+	}
+	if _, err := solveSubstitution("abcdefghijklmnopqrstuvwxyzäöü"); err == nil {
+		t.Errorf("solveSubstitution() expected an error for too many letters")
+	}
+}
+
+func TestSolveSubstitutionLongText(t *testing.T) {
+	// only the start of a long text is scored, the key it finds still has
+	// to decode all of it
+	long := strings.Repeat(solverTestText+" ", 100)
+	encoded, _ := substitutionEncodeSteps(getDefaultCodeMap(), long, false)
+
+	solution, err := solveSubstitution(encoded)
+	if err != nil {
+		t.Fatalf("error in solveSubstitution(): %s", err)
+	}
+	if solution.Plaintext != long {
+		t.Errorf("solveSubstitution() expected the whole text decoded, got: %.100s...", solution.Plaintext)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build cgo && !purego
+
+package main
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver is mattn/go-sqlite3, the original driver, which needs cgo
+const sqliteDriver = "sqlite3"
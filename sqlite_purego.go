@@ -0,0 +1,12 @@
+//go:build !cgo || purego
+
+package main
+
+import (
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDriver is modernc.org/sqlite, which is sqlite translated to Go. It's
+// used when building with -tags purego or CGO_ENABLED=0, so ecc can be
+// cross-compiled into a static binary without gcc.
+const sqliteDriver = "sqlite"
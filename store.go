@@ -0,0 +1,299 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrCodeNotFound is what a CodeStore returns for a path nobody has
+// claimed yet.
+var ErrCodeNotFound = errors.New("code not found")
+
+// CodeStore is where the cipher pages live. Handlers only talk to this, so
+// they don't care whether it's a database or a map in memory.
+type CodeStore interface {
+	// Get returns the value map saved for a path
+	Get(path string) (map[string]string, error)
+	// Create claims a path with a secret, starting it off with valueMap as
+	// version 1
+	Create(path string, secret string, valueMap map[string]string) error
+	// UpdateMap replaces the value map for a path that has been claimed
+	UpdateMap(path string, valueMap map[string]string) error
+	// GetSecretHash returns the bcrypt hash of the secret for a path
+	GetSecretHash(path string) (string, error)
+	// List returns every claimed path in order
+	List() ([]string, error)
+	// Delete removes a path so it can be claimed again
+	Delete(path string) error
+	// History returns every map a path has had, oldest first
+	History(path string) ([]CodeVersion, error)
+	// CurrentVersion returns the number of the newest map a path has had
+	CurrentVersion(path string) (int, error)
+	// GetVersion returns the map a path had at one version
+	GetVersion(path string, version int) (map[string]string, error)
+	// Touch marks a path as still in use, it's fine to call for one that
+	// hasn't been claimed
+	Touch(path string) error
+}
+
+// sqlStore keeps codes in the database, sqlite or postgres. The queries are
+// written so they run on both.
+type sqlStore struct {
+	db *sql.DB
+}
+
+func newSQLStore(db *sql.DB) *sqlStore {
+	return &sqlStore{db: db}
+}
+
+func (store *sqlStore) Get(path string) (map[string]string, error) {
+	myMap, err := getPathCodeMap(store.db, path)
+	if err == sql.ErrNoRows {
+		return myMap, ErrCodeNotFound
+	}
+	return myMap, err
+}
+
+func (store *sqlStore) Create(path string, secret string, valueMap map[string]string) error {
+	return createNewCode(store.db, path, secret, valueMap)
+}
+
+func (store *sqlStore) UpdateMap(path string, valueMap map[string]string) error {
+	if _, err := store.GetSecretHash(path); err != nil {
+		return err
+	}
+	return setPathCodeMap(store.db, path, valueMap)
+}
+
+func (store *sqlStore) GetSecretHash(path string) (string, error) {
+	hash, err := getPathPass(store.db, path)
+	if err != nil {
+		return "", err
+	}
+	// getPathPass gives back nothing at all for a path that isn't there
+	if hash == "" {
+		return "", ErrCodeNotFound
+	}
+	return hash, nil
+}
+
+func (store *sqlStore) List() ([]string, error) {
+	rows, err := store.db.Query("select path from codes order by path")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := []string{}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+func (store *sqlStore) Delete(path string) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	n, err := deleteCode(tx, path)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n == 0 {
+		tx.Rollback()
+		return ErrCodeNotFound
+	}
+	return tx.Commit()
+}
+
+func (store *sqlStore) Touch(path string) error {
+	return touchCode(store.db, path, time.Now().Unix())
+}
+
+func (store *sqlStore) History(path string) ([]CodeVersion, error) {
+	versions, err := getCodeVersions(store.db, path)
+	if err == nil && len(versions) == 0 {
+		return versions, ErrCodeNotFound
+	}
+	return versions, err
+}
+
+func (store *sqlStore) CurrentVersion(path string) (int, error) {
+	version, err := getCurrentVersion(store.db, path)
+	if err == nil && version == 0 {
+		return 0, ErrCodeNotFound
+	}
+	return version, err
+}
+
+func (store *sqlStore) GetVersion(path string, version int) (map[string]string, error) {
+	myMap, err := getCodeVersion(store.db, path, version)
+	if err == sql.ErrNoRows {
+		return myMap, ErrCodeNotFound
+	}
+	return myMap, err
+}
+
+type memoryCode struct {
+	hash     string
+	valueMap map[string]string
+	versions []CodeVersion
+}
+
+// memoryStore keeps codes and players in maps, which is handy for tests.
+// Maps are copied on the way in and out so callers can't change what's
+// stored.
+type memoryStore struct {
+	mu      sync.Mutex
+	codes   map[string]*memoryCode
+	players memoryPlayers
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{codes: make(map[string]*memoryCode), players: newMemoryPlayers()}
+}
+
+func copyMap(myMap map[string]string) map[string]string {
+	copied := make(map[string]string, len(myMap))
+	for k, v := range myMap {
+		copied[k] = v
+	}
+	return copied
+}
+
+func (store *memoryStore) Get(path string) (map[string]string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	code, ok := store.codes[path]
+	if !ok {
+		return make(map[string]string), ErrCodeNotFound
+	}
+	return copyMap(code.valueMap), nil
+}
+
+func (store *memoryStore) Create(path string, secret string, valueMap map[string]string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.codes[path]; ok {
+		return errors.New("code already exists")
+	}
+	store.codes[path] = &memoryCode{hash: string(hash), valueMap: copyMap(valueMap)}
+	store.codes[path].addVersion()
+	return nil
+}
+
+func (store *memoryStore) UpdateMap(path string, valueMap map[string]string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	code, ok := store.codes[path]
+	if !ok {
+		return ErrCodeNotFound
+	}
+	code.valueMap = copyMap(valueMap)
+	code.addVersion()
+	return nil
+}
+
+func (code *memoryCode) addVersion() {
+	code.versions = append(code.versions, CodeVersion{
+		Version:   len(code.versions) + 1,
+		CreatedAt: time.Now().Unix(),
+		ValueMap:  copyMap(code.valueMap),
+	})
+}
+
+func (store *memoryStore) GetSecretHash(path string) (string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	code, ok := store.codes[path]
+	if !ok {
+		return "", ErrCodeNotFound
+	}
+	return code.hash, nil
+}
+
+func (store *memoryStore) List() ([]string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	paths := make([]string, 0, len(store.codes))
+	for path := range store.codes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (store *memoryStore) Delete(path string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.codes[path]; !ok {
+		return ErrCodeNotFound
+	}
+	delete(store.codes, path)
+	return nil
+}
+
+func (store *memoryStore) History(path string) ([]CodeVersion, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	code, ok := store.codes[path]
+	if !ok {
+		return []CodeVersion{}, ErrCodeNotFound
+	}
+	versions := make([]CodeVersion, len(code.versions))
+	for i, version := range code.versions {
+		version.ValueMap = copyMap(version.ValueMap)
+		versions[i] = version
+	}
+	return versions, nil
+}
+
+func (store *memoryStore) CurrentVersion(path string) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	code, ok := store.codes[path]
+	if !ok {
+		return 0, ErrCodeNotFound
+	}
+	return len(code.versions), nil
+}
+
+func (store *memoryStore) GetVersion(path string, version int) (map[string]string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	code, ok := store.codes[path]
+	if !ok || version < 1 || version > len(code.versions) {
+		return make(map[string]string), ErrCodeNotFound
+	}
+	return copyMap(code.versions[version-1].ValueMap), nil
+}
+
+// Touch does nothing, the janitor only cleans up the database
+func (store *memoryStore) Touch(path string) error {
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testCodeStore runs the same checks against any CodeStore so the sqlite
+// and memory stores can't drift apart
+func testCodeStore(t *testing.T, store CodeStore) {
+	if err := store.Create("beta", "secret", getDefaultCodeMap()); err != nil {
+		t.Fatalf("error in Create(): %s", err)
+	}
+	if err := store.Create("alpha", "secret2", getDefaultCodeMap()); err != nil {
+		t.Fatalf("error in Create(): %s", err)
+	}
+	if err := store.Create("alpha", "again", getDefaultCodeMap()); err == nil {
+		t.Errorf("Create() expected an error for a path that is already claimed")
+	}
+
+	myMap, err := store.Get("beta")
+	if err != nil {
+		t.Fatalf("error in Get(): %s", err)
+	}
+	if len(myMap) != 26 || myMap["a"] != "z" {
+		t.Errorf("Get() expected the default map, got: %v", myMap)
+	}
+	if _, err := store.Get("nope"); err != ErrCodeNotFound {
+		t.Errorf("Get() expected ErrCodeNotFound, got: %v", err)
+	}
+
+	myMap["a"] = "q"
+	if err := store.UpdateMap("beta", myMap); err != nil {
+		t.Fatalf("error in UpdateMap(): %s", err)
+	}
+	myMap["a"] = "changed after saving"
+	if saved, _ := store.Get("beta"); saved["a"] != "q" {
+		t.Errorf("UpdateMap() expected a to be q, got: %s", saved["a"])
+	}
+	if err := store.UpdateMap("nope", myMap); err != ErrCodeNotFound {
+		t.Errorf("UpdateMap() expected ErrCodeNotFound, got: %v", err)
+	}
+
+	versions, err := store.History("beta")
+	if err != nil {
+		t.Fatalf("error in History(): %s", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 1 || versions[1].ValueMap["a"] != "q" {
+		t.Errorf("History() expected the default map then the saved one, got: %v", versions)
+	}
+	if first, err := store.GetVersion("beta", 1); err != nil || first["a"] != "z" {
+		t.Errorf("GetVersion() expected a to be z in version 1, got: %v %v", first["a"], err)
+	}
+	if _, err := store.GetVersion("beta", 3); err != ErrCodeNotFound {
+		t.Errorf("GetVersion() expected ErrCodeNotFound, got: %v", err)
+	}
+	if _, err := store.History("nope"); err != ErrCodeNotFound {
+		t.Errorf("History() expected ErrCodeNotFound, got: %v", err)
+	}
+	if version, err := store.CurrentVersion("beta"); err != nil || version != 2 {
+		t.Errorf("CurrentVersion() expected 2, got: %v %v", version, err)
+	}
+	if _, err := store.CurrentVersion("nope"); err != ErrCodeNotFound {
+		t.Errorf("CurrentVersion() expected ErrCodeNotFound, got: %v", err)
+	}
+
+	// a path can start off with its own map
+	if err := store.Create("gamma", "secret3", map[string]string{"a": "b"}); err != nil {
+		t.Fatalf("error in Create(): %s", err)
+	}
+	if first, err := store.GetVersion("gamma", 1); err != nil || first["a"] != "b" {
+		t.Errorf("GetVersion() expected a to be b in version 1, got: %v %v", first["a"], err)
+	}
+	if version, err := store.CurrentVersion("gamma"); err != nil || version != 1 {
+		t.Errorf("CurrentVersion() expected 1, got: %v %v", version, err)
+	}
+
+	hash, err := store.GetSecretHash("beta")
+	if err != nil {
+		t.Fatalf("error in GetSecretHash(): %s", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte("secret")) != nil {
+		t.Errorf("GetSecretHash() did not return a hash of the secret")
+	}
+	if _, err := store.GetSecretHash("nope"); err != ErrCodeNotFound {
+		t.Errorf("GetSecretHash() expected ErrCodeNotFound, got: %v", err)
+	}
+
+	paths, err := store.List()
+	if err != nil {
+		t.Fatalf("error in List(): %s", err)
+	}
+	if len(paths) < 2 || paths[0] != "alpha" {
+		t.Errorf("List() expected alpha first, got: %v", paths)
+	}
+
+	if err := store.Delete("alpha"); err != nil {
+		t.Fatalf("error in Delete(): %s", err)
+	}
+	if _, err := store.Get("alpha"); err != ErrCodeNotFound {
+		t.Errorf("Delete() did not remove alpha")
+	}
+	if err := store.Delete("alpha"); err != ErrCodeNotFound {
+		t.Errorf("Delete() expected ErrCodeNotFound, got: %v", err)
+	}
+
+	// whoever claims a deleted path doesn't get the old history
+	if err := store.Create("alpha", "new owner", getDefaultCodeMap()); err != nil {
+		t.Fatalf("error in Create(): %s", err)
+	}
+	if versions, _ := store.History("alpha"); len(versions) != 1 {
+		t.Errorf("History() expected 1 version after claiming alpha again, got: %v", versions)
+	}
+	if err := store.Touch("alpha"); err != nil {
+		t.Errorf("error in Touch(): %s", err)
+	}
+	if err := store.Touch("nope"); err != nil {
+		t.Errorf("Touch() expected no error for a path that isn't claimed, got: %s", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testCodeStore(t, newMemoryStore())
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testCodeStore(t, newSQLStore(setupTestDB(t)))
+}
+
+func setupTestStore(t *testing.T) CodeStore {
+	store := newMemoryStore()
+	if err := store.Create("testpath", "password123", getDefaultCodeMap()); err != nil {
+		t.Errorf("unable to create testing store: %s", err)
+	}
+	return store
+}
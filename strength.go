@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	strengthWeak   = "weak"
+	strengthOkay   = "okay"
+	strengthStrong = "strong"
+
+	keyboardOrder = "qwertyuiopasdfghjklzxcvbnm"
+)
+
+// CipherStrength is how hard a code map would be to guess, and why.
+type CipherStrength struct {
+	Rating      string
+	Percent     int
+	Family      string
+	KeySpace    string
+	FixedPoints []string
+	Warnings    []string
+}
+
+// bits of key in a completely random substitution, log2(26!)
+var substitutionBits = func() float64 {
+	bits := 0.0
+	for i := 2; i <= 26; i++ {
+		bits += math.Log2(float64(i))
+	}
+	return bits
+}()
+
+// rateCipher looks for the easy to guess patterns in a code map. Even the
+// strongest substitution cipher falls to frequency analysis, so this is
+// about how long it takes somebody to guess the key, not whether they can.
+func rateCipher(myMap map[string]string) *CipherStrength {
+	keys := make([]string, 0, len(myMap))
+	for k := range myMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	strength := &CipherStrength{}
+	usedBy := make(map[string][]string)
+	for _, k := range keys {
+		v := myMap[k]
+		switch {
+		case v == "":
+			strength.Warnings = append(strength.Warnings, k+" doesn't have anything under it, so it disappears from your messages")
+		case v == k:
+			strength.FixedPoints = append(strength.FixedPoints, k)
+		}
+		if v != "" {
+			usedBy[v] = append(usedBy[v], k)
+		}
+	}
+
+	duplicates := 0
+	symbols := make([]string, 0, len(usedBy))
+	for v := range usedBy {
+		symbols = append(symbols, v)
+	}
+	sort.Strings(symbols)
+	for _, v := range symbols {
+		if len(usedBy[v]) > 1 {
+			duplicates++
+			strength.Warnings = append(strength.Warnings, v+" is used for "+strings.Join(usedBy[v], " and ")+", so nobody can be sure which one you meant")
+		}
+	}
+	if len(strength.FixedPoints) > 0 {
+		strength.Warnings = append(strength.Warnings, fmt.Sprintf("%v letters stay the same (%s), anyone reading your message gets those for free", len(strength.FixedPoints), strings.Join(strength.FixedPoints, ", ")))
+	}
+
+	bits := substitutionBits
+	strength.Family = "mixed up substitution"
+	strength.KeySpace = "26! (about 400,000,000,000,000,000,000,000,000) possible keys"
+
+	values := ""
+	for _, k := range keys {
+		values += myMap[k]
+	}
+	if a, b, ok := affineKey(myMap); ok {
+		switch {
+		case a == 1 && b == 0:
+			strength.Family = "no cipher at all"
+			strength.KeySpace = "every letter is itself, so there is nothing to guess"
+			bits = 0
+		case a == 1:
+			strength.Family = fmt.Sprintf("caesar shift of %v", b)
+			strength.KeySpace = "25 possible shifts, you can try them all in a few minutes"
+			bits = math.Log2(25)
+			strength.Warnings = append(strength.Warnings, "every letter moves the same amount, so guessing one letter gives away the rest")
+		case a == 25 && b == 25:
+			strength.Family = "atbash (the alphabet backwards)"
+			strength.KeySpace = "just 1, atbash is one of the first things a code breaker tries"
+			bits = 1
+			strength.Warnings = append(strength.Warnings, "this is the alphabet backwards, which is also the cipher every new page starts with")
+		default:
+			strength.Family = fmt.Sprintf("affine cipher (times %v plus %v)", a, b)
+			strength.KeySpace = "312 possible affine keys, a computer tries them all instantly"
+			bits = math.Log2(312)
+			strength.Warnings = append(strength.Warnings, "the letters follow a math rule, so a few guesses give away the rest")
+		}
+	} else if values == keyboardOrder {
+		strength.Family = "keyboard order"
+		strength.KeySpace = "just 1, reading off the keyboard is easy to guess"
+		bits = 1
+		strength.Warnings = append(strength.Warnings, "the letters are in the same order as a keyboard")
+	} else if keyword, ok := keywordPrefix(values); ok {
+		strength.Family = "keyword alphabet (" + keyword + ")"
+		strength.KeySpace = "about as many keys as there are words in the dictionary"
+		bits = math.Log2(100000)
+		strength.Warnings = append(strength.Warnings, "after "+keyword+" the rest of the alphabet is in order, so guessing the key word gives away everything")
+	}
+
+	// every letter that stays the same or gets mixed up with another one
+	// makes the cipher easier
+	percent := bits / substitutionBits * 100
+	percent -= float64(len(strength.FixedPoints)) * 5
+	percent -= float64(duplicates) * 10
+	strength.Percent = int(math.Max(2, math.Min(100, math.Round(percent))))
+
+	switch {
+	case strength.Percent < 25:
+		strength.Rating = strengthWeak
+	case strength.Percent < 70:
+		strength.Rating = strengthOkay
+	default:
+		strength.Rating = strengthStrong
+	}
+	return strength
+}
+
+// affineKey checks if every letter is encoded as (a * letter + b) mod 26,
+// which covers caesar shifts and atbash too
+func affineKey(myMap map[string]string) (int, int, bool) {
+	letterAt := func(r rune) (int, bool) {
+		v := myMap[string(r)]
+		if len(v) != 1 || v[0] < 'a' || v[0] > 'z' {
+			return 0, false
+		}
+		return int(v[0] - 'a'), true
+	}
+
+	b, ok := letterAt('a')
+	if !ok {
+		return 0, 0, false
+	}
+	next, ok := letterAt('b')
+	if !ok {
+		return 0, 0, false
+	}
+	a := (next - b + 26) % 26
+	// a has to share no factors with 26 or two letters end up with the same
+	// code, and there's no way back
+	if gcd(a, 26) != 1 {
+		return 0, 0, false
+	}
+
+	for r := 'a'; r <= 'z'; r++ {
+		v, ok := letterAt(r)
+		if !ok || v != (a*int(r-'a')+b)%26 {
+			return 0, 0, false
+		}
+	}
+	return a, b, true
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// keywordPrefix spots a key word followed by the rest of the alphabet in
+// order, like "secrtabdfg...". Only maps onto the 26 letters count, symbols
+// like "!@#" are in order by accident.
+func keywordPrefix(values string) (string, bool) {
+	if len(values) != 26 {
+		return "", false
+	}
+	seen := make(map[rune]bool)
+	for _, char := range values {
+		if char < 'a' || char > 'z' || seen[char] {
+			return "", false
+		}
+		seen[char] = true
+	}
+	start := 25
+	for start > 0 && values[start-1] < values[start] {
+		start--
+	}
+	if start == 0 || start > 12 {
+		return "", false
+	}
+	return values[:start], true
+}
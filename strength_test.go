@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRateCipher(t *testing.T) {
+	tests := []struct {
+		name   string
+		myMap  map[string]string
+		family string
+		rating string
+	}{
+		{"identity", shiftCodeMap(0), "no cipher at all", strengthWeak},
+		{"shift", shiftCodeMap(3), "caesar shift of 3", strengthWeak},
+		{"atbash", getDefaultCodeMap(), "atbash (the alphabet backwards)", strengthWeak},
+		{"keyboard", keyMapFromString(keyboardOrder), "keyboard order", strengthWeak},
+		{"keyword", keyMapFromString("spyabcdefghijklmnoqrtuvwxz"), "keyword alphabet (spy)", strengthWeak},
+		{"random", keyMapFromString("qmzjdtwyhaxkepsbvgolcrnuif"), "mixed up substitution", strengthStrong},
+		{"affine", affineCodeMap(5, 8), "affine cipher (times 5 plus 8)", strengthWeak},
+		// 13 shares a factor with 26, so half the letters are missing
+		{"not affine", affineCodeMap(13, 2), "mixed up substitution", strengthStrong},
+		{"symbols", keyMapFromString("zq!\"#$%&'()*+,-./012345678"), "mixed up substitution", strengthStrong},
+	}
+
+	for _, test := range tests {
+		strength := rateCipher(test.myMap)
+		if strength.Family != test.family {
+			t.Errorf("rateCipher() %s expected %s, got: %s", test.name, test.family, strength.Family)
+		}
+		if strength.Rating != test.rating {
+			t.Errorf("rateCipher() %s expected %s, got: %s (%v%%)", test.name, test.rating, strength.Rating, strength.Percent)
+		}
+	}
+}
+
+func TestRateCipherWarnings(t *testing.T) {
+	myMap := keyMapFromString("qmzjdtwyhaxkepsbvgolcrnuif")
+	myMap["a"] = "a"
+	myMap["b"] = "a"
+	myMap["c"] = ""
+
+	strength := rateCipher(myMap)
+	if len(strength.FixedPoints) != 1 || strength.FixedPoints[0] != "a" {
+		t.Errorf("rateCipher() expected a to be a fixed point, got: %v", strength.FixedPoints)
+	}
+	// c is empty, a and b share a symbol, and a stays the same
+	if len(strength.Warnings) != 3 {
+		t.Errorf("rateCipher() expected 3 warnings, got: %v", strength.Warnings)
+	}
+	if strength.Percent != 85 {
+		t.Errorf("rateCipher() expected 85%%, got: %v", strength.Percent)
+	}
+}
+
+func keyMapFromString(values string) map[string]string {
+	myMap := make(map[string]string)
+	for i, r := range values {
+		myMap[string(rune('a'+i))] = string(r)
+	}
+	return myMap
+}
+
+func affineCodeMap(a int, b int) map[string]string {
+	myMap := make(map[string]string)
+	for i := 0; i < 26; i++ {
+		myMap[string(rune('a'+i))] = string(rune('a' + (a*i+b)%26))
+	}
+	return myMap
+}
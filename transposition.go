@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// GridCell is one square of a transposition grid. Step is the order the
+// letter gets read off the grid (starting at 1), or 0 for an empty square.
+type GridCell struct {
+	Char  string
+	Step  int
+	Delay int // milliseconds before the square lights up
+}
+
+// CipherGrid is the grid a transposition cipher writes its letters into.
+type CipherGrid struct {
+	Rows [][]GridCell
+}
+
+type gridPos struct {
+	row, col int
+}
+
+// the letters are always written into the grid row by row, the cipher
+// is in the order they are read back out. The steps for the explain view
+// are only built when explain is set.
+func transposeSteps(opts CipherOptions, input string, decode bool, explain bool) (string, *CipherGrid, []ExplainStep) {
+	chars := []rune{}
+	for _, char := range input {
+		if !unicode.IsSpace(char) {
+			chars = append(chars, char)
+		}
+	}
+	n := len(chars)
+	if n == 0 {
+		return "", nil, nil
+	}
+	// a grid wider or taller than the text reads the same as one that
+	// fits it exactly, and a huge size from the form would allocate a
+	// huge grid
+	if opts.Size > n {
+		opts.Size = n
+	}
+
+	rows, cols := gridDimensions(opts, n)
+	path := gridPath(opts, rows, cols, n)
+
+	grid := make([]rune, rows*cols)
+	if decode {
+		for i, pos := range path {
+			grid[pos.row*cols+pos.col] = chars[i]
+		}
+	} else {
+		copy(grid, chars)
+	}
+
+	out := make([]rune, 0, n)
+	if decode {
+		out = append(out, grid[:n]...)
+	} else {
+		for _, pos := range path {
+			out = append(out, grid[pos.row*cols+pos.col])
+		}
+	}
+
+	steps := make([]int, rows*cols)
+	for i, pos := range path {
+		steps[pos.row*cols+pos.col] = i + 1
+	}
+
+	var explainSteps []ExplainStep
+	if explain {
+		explainSteps = make([]ExplainStep, n)
+		for i, pos := range path {
+			char := string(grid[pos.row*cols+pos.col])
+			where := fmt.Sprintf("row %v, column %v", pos.row+1, pos.col+1)
+			if decode {
+				explainSteps[i] = ExplainStep{char, fmt.Sprintf("%s letter in, goes in %s", ordinal(i+1), where), char}
+			} else {
+				explainSteps[pos.row*cols+pos.col] = ExplainStep{char, fmt.Sprintf("written in %s, read out %v", where, ordinal(i+1)), char}
+			}
+		}
+	}
+
+	cipherGrid := &CipherGrid{Rows: make([][]GridCell, rows)}
+	for r := 0; r < rows; r++ {
+		cipherGrid.Rows[r] = make([]GridCell, cols)
+		for c := 0; c < cols; c++ {
+			idx := r*cols + c
+			if idx < n {
+				cipherGrid.Rows[r][c] = GridCell{
+					Char:  string(grid[idx]),
+					Step:  steps[idx],
+					Delay: steps[idx] * 300,
+				}
+			}
+		}
+	}
+
+	return string(out), cipherGrid, explainSteps
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%v%s", n, suffix)
+}
+
+// a scytale has one row per side of the rod, a route grid has a fixed
+// number of columns
+func gridDimensions(opts CipherOptions, n int) (int, int) {
+	if opts.Type == cipherScytale {
+		return opts.Size, (n + opts.Size - 1) / opts.Size
+	}
+	return (n + opts.Size - 1) / opts.Size, opts.Size
+}
+
+// gridPath returns the order the squares are read in. Squares past the end
+// of the text are skipped so no padding is needed.
+func gridPath(opts CipherOptions, rows int, cols int, n int) []gridPos {
+	path := []gridPos{}
+	add := func(r, c int) {
+		if r*cols+c < n {
+			path = append(path, gridPos{r, c})
+		}
+	}
+
+	switch {
+	case opts.Type == cipherScytale:
+		// unwinding the strip reads straight down each column
+		for c := 0; c < cols; c++ {
+			for r := 0; r < rows; r++ {
+				add(r, c)
+			}
+		}
+	case opts.Route == routeSnake:
+		// down the first column, up the next, and so on
+		for c := 0; c < cols; c++ {
+			for i := 0; i < rows; i++ {
+				if c%2 == 0 {
+					add(i, c)
+				} else {
+					add(rows-1-i, c)
+				}
+			}
+		}
+	default:
+		// clockwise from the top left corner in towards the middle
+		top, bottom, left, right := 0, rows-1, 0, cols-1
+		for top <= bottom && left <= right {
+			for c := left; c <= right; c++ {
+				add(top, c)
+			}
+			for r := top + 1; r <= bottom; r++ {
+				add(r, right)
+			}
+			if top < bottom {
+				for c := right - 1; c >= left; c-- {
+					add(bottom, c)
+				}
+			}
+			if left < right {
+				for r := bottom - 1; r > top; r-- {
+					add(r, left)
+				}
+			}
+			top++
+			bottom--
+			left++
+			right--
+		}
+	}
+
+	return path
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTransposeScytale(t *testing.T) {
+	opts := CipherOptions{Type: cipherScytale, Size: 3}
+
+	encoded, grid, _ := transposeSteps(opts, "we are discovered", false, false)
+	if encoded != "wdveieasrrceeod" {
+		t.Errorf("transposeSteps() expected wdveieasrrceeod, got: %s", encoded)
+	}
+	if len(grid.Rows) != 3 || len(grid.Rows[0]) != 5 {
+		t.Errorf("transposeSteps() expected a 3x5 grid, got: %vx%v", len(grid.Rows), len(grid.Rows[0]))
+	}
+	if grid.Rows[1][0].Char != "d" || grid.Rows[1][0].Step != 2 {
+		t.Errorf("transposeSteps() expected d read second, got: %s read %v", grid.Rows[1][0].Char, grid.Rows[1][0].Step)
+	}
+
+	decoded, _, _ := transposeSteps(opts, encoded, true, false)
+	if decoded != "wearediscovered" {
+		t.Errorf("transposeSteps() expected wearediscovered, got: %s", decoded)
+	}
+}
+
+func TestTransposeRoute(t *testing.T) {
+	tests := []struct {
+		route    string
+		expected string
+	}{
+		{routeSpiral, "abcfijgdeh"},
+		{routeSnake, "adgjhebcfi"},
+	}
+
+	for _, test := range tests {
+		opts := CipherOptions{Type: cipherRoute, Size: 3, Route: test.route}
+		encoded, grid, _ := transposeSteps(opts, "abcdefghij", false, false)
+		if encoded != test.expected {
+			t.Errorf("transposeSteps() %s expected %s, got: %s", test.route, test.expected, encoded)
+		}
+		// the last row only has one letter in it
+		if grid.Rows[3][1].Step != 0 {
+			t.Errorf("transposeSteps() %s expected an empty square, got: %v", test.route, grid.Rows[3][1])
+		}
+
+		decoded, _, _ := transposeSteps(opts, encoded, true, false)
+		if decoded != "abcdefghij" {
+			t.Errorf("transposeSteps() %s expected abcdefghij, got: %s", test.route, decoded)
+		}
+	}
+}
+
+func TestTransposeEmpty(t *testing.T) {
+	encoded, grid, _ := transposeSteps(CipherOptions{Type: cipherScytale, Size: 4}, "   ", false, false)
+	if encoded != "" || grid != nil {
+		t.Errorf("transposeSteps() expected nothing back for blank input, got: %s %v", encoded, grid)
+	}
+}
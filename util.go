@@ -1,15 +1,20 @@
 package main
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const sessionCookie = "ecc_session"
+
 // unicode FTW
 func getDefaultCodeMap() map[string]string {
 	myMap := make(map[string]string)
@@ -54,13 +59,19 @@ func setPathCodeMap(db *sql.DB, path string, newMap map[string]string) error {
 		return err
 	}
 
-	stmt, err := tx.Prepare("update codes set valueMap = ? where path = ?")
+	stmt, err := tx.Prepare("update codes set valueMap = ?, last_used_at = ? where path = ?")
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
 
-	if _, err := stmt.Exec(string(b), path); err != nil {
+	if _, err := stmt.Exec(string(b), time.Now().Unix(), path); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := addCodeVersion(tx, path, string(b)); err != nil {
+		tx.Rollback()
 		return err
 	}
 	err = tx.Commit()
@@ -90,7 +101,7 @@ func getPathPass(db *sql.DB, path string) (string, error) {
 	return currentPathPass, nil
 }
 
-func createNewCode(db *sql.DB, path string, pass string) error {
+func createNewCode(db *sql.DB, path string, pass string, valueMap map[string]string) error {
 	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.MinCost)
 	if err != nil {
 		return err
@@ -101,18 +112,26 @@ func createNewCode(db *sql.DB, path string, pass string) error {
 		return err
 	}
 
-	stmt, err := tx.Prepare("insert into codes(path, password, valueMap) values(?, ?, ?)")
+	stmt, err := tx.Prepare("insert into codes(path, password, valueMap, created_at, last_used_at) values(?, ?, ?, ?, ?)")
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
 
-	b, err := json.Marshal(getDefaultCodeMap())
+	b, err := json.Marshal(valueMap)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
-	_, err = stmt.Exec(path, string(hash), string(b))
+	now := time.Now().Unix()
+	_, err = stmt.Exec(path, string(hash), string(b), now, now)
 	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := addCodeVersion(tx, path, string(b)); err != nil {
+		tx.Rollback()
 		return err
 	}
 	err = tx.Commit()
@@ -122,8 +141,85 @@ func createNewCode(db *sql.DB, path string, pass string) error {
 	return nil
 }
 
-func isClaimed(db *sql.DB, path string) bool {
-	currentPass, _ := getPathPass(db, path)
+// touchCode marks a page as used, so the janitor leaves it alone. It only
+// writes once in a while, not on every page view.
+func touchCode(db *sql.DB, path string, now int64) error {
+	_, err := db.Exec("update codes set last_used_at = ? where path = ? and last_used_at < ?", now, path, now-touchEvery)
+	return err
+}
+
+// deleteCode removes a page along with its history, so whoever claims the
+// path next starts from nothing
+func deleteCode(tx *sql.Tx, path string) (int64, error) {
+	if _, err := tx.Exec("delete from code_versions where path = ?", path); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec("delete from codes where path = ?", path)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// addCodeVersion keeps a copy of every map a path has had, so messages
+// written with an old one can still be decoded
+func addCodeVersion(tx *sql.Tx, path string, valueMap string) error {
+	// postgres can't tell what type a parameter is in an insert-select, so
+	// look up the next version on its own
+	var version int
+	err := tx.QueryRow("select coalesce(max(version), 0) + 1 from code_versions where path = ?", path).Scan(&version)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("insert into code_versions(path, version, valueMap, created_at) values(?, ?, ?, ?)", path, version, valueMap, time.Now().Unix())
+	return err
+}
+
+func getCodeVersions(db *sql.DB, path string) ([]CodeVersion, error) {
+	rows, err := db.Query("select version, valueMap, created_at from code_versions where path = ? order by version", path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []CodeVersion{}
+	for rows.Next() {
+		var version CodeVersion
+		var valueMapDB string
+		if err := rows.Scan(&version.Version, &valueMapDB, &version.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(valueMapDB), &version.ValueMap); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// getCurrentVersion is the newest version a path has, or 0 if it has none
+func getCurrentVersion(db *sql.DB, path string) (int, error) {
+	var version int
+	err := db.QueryRow("select coalesce(max(version), 0) from code_versions where path = ?", path).Scan(&version)
+	return version, err
+}
+
+func getCodeVersion(db *sql.DB, path string, version int) (map[string]string, error) {
+	var valueMapDB string
+	err := db.QueryRow("select valueMap from code_versions where path = ? and version = ?", path, version).Scan(&valueMapDB)
+	if err != nil {
+		return make(map[string]string), err
+	}
+
+	var codeTable map[string]string
+	if err = json.Unmarshal([]byte(valueMapDB), &codeTable); err != nil {
+		return make(map[string]string), err
+	}
+	return codeTable, nil
+}
+
+func isClaimed(store CodeStore, path string) bool {
+	currentPass, _ := store.GetSecretHash(path)
 	if currentPass != "" {
 		return true
 	}
@@ -139,3 +235,30 @@ func comparePasswords(hashedPwd string, plainPwd string) bool {
 
 	return true
 }
+
+// getSessionID returns the id from the session cookie, handing out a new
+// one if the browser doesn't have one yet
+func getSessionID(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(sessionCookie); err == nil && len(cookie.Value) == 32 {
+		return cookie.Value
+	}
+
+	id := randomID(16)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
+
+// randomID makes a hex id out of n random bytes
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		log.Println("unable to make random id: ", err)
+	}
+	return hex.EncodeToString(b)
+}
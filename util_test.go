@@ -2,17 +2,11 @@ package main
 
 import (
 	"database/sql"
-	"os"
 	"testing"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-var CREATE_TABLE_SQL = `
-        create table codes (path text not null primary key, password text, valueMap text);
-        delete from codes;
-        `
-
 func TestGetDefaultCodeMap(t *testing.T) {
 	testMap := getDefaultCodeMap()
 	// TODO: test moar
@@ -75,7 +69,7 @@ func TestGetPathPass(t *testing.T) {
 
 func TestCreateNewCode(t *testing.T) {
 	testDB := setupTestDB(t)
-	err := createNewCode(testDB, "testerpath2", "testpassword")
+	err := createNewCode(testDB, "testerpath2", "testpassword", getDefaultCodeMap())
 	if err != nil {
 		t.Errorf("error in createNewCode(): %s", err)
 	}
@@ -97,15 +91,15 @@ func TestCreateNewCode(t *testing.T) {
 }
 
 func TestIsClaimed(t *testing.T) {
-	// isClaimed(db *sql.DB, path string)
+	// isClaimed(store CodeStore, path string)
 
 	testDB := setupTestDB(t)
-	shouldPass := isClaimed(testDB, "testpath")
+	shouldPass := isClaimed(newSQLStore(testDB), "testpath")
 	if !shouldPass {
 		t.Errorf("expected true, got: %v", shouldPass)
 	}
 
-	shouldFail := isClaimed(testDB, "doesnotcompute")
+	shouldFail := isClaimed(newSQLStore(testDB), "doesnotcompute")
 	if shouldFail {
 		t.Errorf("expected false, got: %v", shouldFail)
 	}
@@ -113,24 +107,25 @@ func TestIsClaimed(t *testing.T) {
 
 func setupTestDB(t *testing.T) *sql.DB {
 
-	// setup test db
-	db, err := sql.Open("sqlite3", "./testing-only.db")
+	// setup test db, in memory so there's no file to clean up. Every
+	// connection to :memory: gets its own database, so only have one.
+	db, err := sql.Open(sqliteDriver, ":memory:")
 	if err != nil {
 		t.Errorf("unable to create testing db: %s", err)
 	}
+	db.SetMaxOpenConns(1)
 
-	_, err = db.Exec(CREATE_TABLE_SQL)
+	_, err = migrateDB(db)
 	if err != nil {
 		t.Errorf("unable to create testing db table structure: %s", err)
 	}
 
 	// TODO: should we eat our own dogfood here?
 	// insert dummy data
-	createNewCode(db, "testpath", "password123")
+	createNewCode(db, "testpath", "password123", getDefaultCodeMap())
 
 	t.Cleanup(func() {
 		db.Close()
-		os.Remove("./testing-only.db")
 	})
 
 	return db
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// more than this isn't needed to break a key and keeps one request from
+	// tying up the server
+	vigenereMaxInput     = 20000
+	vigenereMaxKeyLength = 12
+	kasiskiMinLength     = 3
+	kasiskiMaxLength     = 6
+	kasiskiMaxShown      = 15
+	// columns less sure than this get flagged, the key letter might be off
+	vigenereSureColumn = 90
+)
+
+// VigenereReport walks through breaking a vigenere cipher one step at a
+// time: repeats and their spacings, index of coincidence for each key
+// length, then a caesar crack of every column.
+type VigenereReport struct {
+	Input        string
+	Letters      int
+	Repeats      []KasiskiRepeat
+	Factors      []FactorCount
+	KeyLengths   []KeyLengthIoC
+	KeyLength    int
+	ChosenLength bool
+	Columns      []ColumnSolve
+	Key          string
+	Unsure       bool
+	Plaintext    string
+}
+
+// KasiskiRepeat is a run of letters that shows up more than once, and how
+// far apart each time is from the first.
+type KasiskiRepeat struct {
+	Sequence  string
+	Positions []int
+	Spacings  []int
+}
+
+// FactorCount is how many repeat spacings a possible key length divides.
+type FactorCount struct {
+	Factor int
+	Count  int
+}
+
+// KeyLengthIoC is the average index of coincidence of the columns you get
+// by splitting the text up for a key of this length.
+type KeyLengthIoC struct {
+	Length int
+	IoC    float64
+	Score  string
+	Best   bool
+}
+
+// ColumnSolve is the caesar shift that makes one column look most english.
+// Confidence is how likely that shift is next to the other 25, short
+// columns often have a runner up that fits nearly as well.
+type ColumnSolve struct {
+	Column     int
+	Letters    int
+	KeyLetter  string
+	Score      string
+	Confidence int
+	RunnerUp   string
+	Unsure     bool
+}
+
+// vigenereKeyLetters is the key with anything that isn't a-z taken out, or
+// nil when that leaves nothing to shift by
+func vigenereKeyLetters(key string) []rune {
+	var keyLetters []rune
+	for _, char := range strings.ToLower(key) {
+		if char >= 'a' && char <= 'z' {
+			keyLetters = append(keyLetters, char)
+		}
+	}
+	return keyLetters
+}
+
+// vigenereShiftSteps moves each letter along by the matching key letter.
+// The steps for the explain view are only built when explain is set.
+func vigenereShiftSteps(key string, input string, decode bool, explain bool) (string, []ExplainStep) {
+	keyLetters := vigenereKeyLetters(key)
+	if len(keyLetters) == 0 {
+		return "", nil
+	}
+
+	// the key only moves along on letters, everything else passes through
+	var valToReturn strings.Builder
+	steps := []ExplainStep{}
+	i := 0
+	for _, char := range strings.ToLower(input) {
+		if char < 'a' || char > 'z' {
+			valToReturn.WriteRune(char)
+			if explain {
+				steps = append(steps, ExplainStep{string(char), "not a letter, stays the same", string(char)})
+			}
+			continue
+		}
+		keyLetter := keyLetters[i%len(keyLetters)]
+		shift := int(keyLetter - 'a')
+		if decode {
+			shift = 26 - shift
+		}
+		out := 'a' + (char-'a'+rune(shift))%26
+		valToReturn.WriteRune(out)
+		if explain {
+			rule := fmt.Sprintf("key letter %c, move forward %v", keyLetter, keyLetter-'a')
+			if decode {
+				rule = fmt.Sprintf("key letter %c, move back %v", keyLetter, keyLetter-'a')
+			}
+			steps = append(steps, ExplainStep{string(char), rule, string(out)})
+		}
+		i++
+	}
+	return valToReturn.String(), steps
+}
+
+// indexOfCoincidence is the chance that two letters picked from the text
+// are the same. English is around 0.066, random letters around 0.038.
+func indexOfCoincidence(letters string) float64 {
+	if len(letters) < 2 {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, char := range letters {
+		counts[char]++
+	}
+	sum := 0
+	for _, count := range counts {
+		sum += count * (count - 1)
+	}
+	return float64(sum) / float64(len(letters)*(len(letters)-1))
+}
+
+// columns splits letters into keyLength columns, the first holds every
+// letter encoded with the first letter of the key and so on
+func columns(letters string, keyLength int) []string {
+	cols := make([]strings.Builder, keyLength)
+	for i, char := range letters {
+		cols[i%keyLength].WriteRune(char)
+	}
+	out := make([]string, keyLength)
+	for i := range cols {
+		out[i] = cols[i].String()
+	}
+	return out
+}
+
+func kasiski(letters string) ([]KasiskiRepeat, []FactorCount) {
+	repeats := []KasiskiRepeat{}
+	for length := kasiskiMaxLength; length >= kasiskiMinLength; length-- {
+		positions := make(map[string][]int)
+		order := []string{}
+		for i := 0; i+length <= len(letters); i++ {
+			seq := letters[i : i+length]
+			if _, ok := positions[seq]; !ok {
+				order = append(order, seq)
+			}
+			positions[seq] = append(positions[seq], i)
+		}
+		for _, seq := range order {
+			if len(positions[seq]) < 2 {
+				continue
+			}
+			repeat := KasiskiRepeat{Sequence: seq, Positions: positions[seq]}
+			for _, pos := range positions[seq][1:] {
+				repeat.Spacings = append(repeat.Spacings, pos-positions[seq][0])
+			}
+			repeats = append(repeats, repeat)
+		}
+	}
+
+	factors := []FactorCount{}
+	for factor := 2; factor <= vigenereMaxKeyLength; factor++ {
+		count := 0
+		for _, repeat := range repeats {
+			for _, spacing := range repeat.Spacings {
+				if spacing%factor == 0 {
+					count++
+				}
+			}
+		}
+		factors = append(factors, FactorCount{factor, count})
+	}
+	sort.SliceStable(factors, func(i, j int) bool {
+		return factors[i].Count > factors[j].Count
+	})
+
+	if len(repeats) > kasiskiMaxShown {
+		repeats = repeats[:kasiskiMaxShown]
+	}
+	return repeats, factors
+}
+
+// analyzeVigenere runs every step of the attack. If keyLength is 0 the key
+// length with the best index of coincidence is used.
+func analyzeVigenere(input string, keyLength int) *VigenereReport {
+	var lettersOnly strings.Builder
+	for _, char := range strings.ToLower(input) {
+		if char >= 'a' && char <= 'z' {
+			lettersOnly.WriteRune(char)
+		}
+	}
+	letters := lettersOnly.String()
+	if len(letters) == 0 {
+		return nil
+	}
+
+	if keyLength > len(letters) {
+		keyLength = len(letters)
+	}
+
+	report := &VigenereReport{
+		Input:        input,
+		Letters:      len(letters),
+		ChosenLength: keyLength > 0,
+	}
+	report.Repeats, report.Factors = kasiski(letters)
+
+	best := 0.0
+	for length := 1; length <= vigenereMaxKeyLength && length <= len(letters); length++ {
+		total := 0.0
+		for _, col := range columns(letters, length) {
+			total += indexOfCoincidence(col)
+		}
+		ioc := total / float64(length)
+		report.KeyLengths = append(report.KeyLengths, KeyLengthIoC{
+			Length: length,
+			IoC:    ioc,
+			Score:  fmt.Sprintf("%.4f", ioc),
+		})
+		if ioc > best {
+			best = ioc
+		}
+	}
+
+	// multiples of the real key length score just as well, so go with the
+	// shortest one that is nearly as good as the best
+	if keyLength <= 0 {
+		for _, candidate := range report.KeyLengths {
+			if candidate.IoC >= best*0.9 {
+				keyLength = candidate.Length
+				break
+			}
+		}
+	}
+	for i := range report.KeyLengths {
+		report.KeyLengths[i].Best = report.KeyLengths[i].Length == keyLength
+	}
+	report.KeyLength = keyLength
+
+	for i, col := range columns(letters, keyLength) {
+		column := solveColumn(col)
+		column.Column = i + 1
+		report.Columns = append(report.Columns, column)
+		report.Key += column.KeyLetter
+		report.Unsure = report.Unsure || column.Unsure
+	}
+	report.Plaintext, _ = vigenereShiftSteps(report.Key, input, true, false)
+
+	return report
+}
+
+// solveColumn tries all 26 shifts on a column and keeps the one with the
+// lowest chi-squared. How sure it is comes from how likely english would be
+// to give that shift's letter counts, next to the chances of all the others.
+func solveColumn(col string) ColumnSolve {
+	counts, total := letterCounts(col)
+	scores := make([]float64, 26)
+	logLikelihoods := make([]float64, 26)
+	best := 0
+	for shift := 0; shift < 26; shift++ {
+		scores[shift] = shiftedChiSquared(counts, total, shift)
+		for letter := 0; letter < 26; letter++ {
+			logLikelihoods[shift] += counts[(letter+shift)%26] * math.Log(englishLetterFreq[string(rune('a'+letter))]/100)
+		}
+		if scores[shift] < scores[best] {
+			best = shift
+		}
+	}
+
+	chances, runnerUp := 0.0, -1
+	for shift, logLikelihood := range logLikelihoods {
+		chances += math.Exp(logLikelihood - logLikelihoods[best])
+		if shift != best && (runnerUp == -1 || logLikelihood > logLikelihoods[runnerUp]) {
+			runnerUp = shift
+		}
+	}
+	confidence := int(math.Floor(100 / chances))
+	return ColumnSolve{
+		Letters:    len(col),
+		KeyLetter:  string(rune('a' + best)),
+		Score:      fmt.Sprintf("%.1f", scores[best]),
+		Confidence: confidence,
+		RunnerUp:   string(rune('a' + runnerUp)),
+		Unsure:     confidence < vigenereSureColumn,
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestVigenere(t *testing.T) {
+	encoded, _ := vigenereShiftSteps("lemon", "Attack at dawn!", false, false)
+	if encoded != "lxfopv ef rnhr!" {
+		t.Errorf("vigenereShiftSteps() expected lxfopv ef rnhr!, got: %s", encoded)
+	}
+
+	decoded, _ := vigenereShiftSteps("lemon", encoded, true, false)
+	if decoded != "attack at dawn!" {
+		t.Errorf("vigenereShiftSteps() expected attack at dawn!, got: %s", decoded)
+	}
+
+	if encoded, _ := vigenereShiftSteps("123", "abc", false, false); encoded != "" {
+		t.Errorf("vigenereShiftSteps() expected nothing back without a key")
+	}
+}
+
+func TestIndexOfCoincidence(t *testing.T) {
+	if ioc := indexOfCoincidence("aaaa"); ioc != 1 {
+		t.Errorf("indexOfCoincidence() expected 1, got: %v", ioc)
+	}
+	if ioc := indexOfCoincidence("abcd"); ioc != 0 {
+		t.Errorf("indexOfCoincidence() expected 0, got: %v", ioc)
+	}
+}
+
+func TestKasiski(t *testing.T) {
+	repeats, factors := kasiski("abcdefgabchijkabc")
+	if len(repeats) != 1 || repeats[0].Sequence != "abc" {
+		t.Fatalf("kasiski() expected abc to repeat, got: %v", repeats)
+	}
+	if repeats[0].Spacings[0] != 7 || repeats[0].Spacings[1] != 14 {
+		t.Errorf("kasiski() expected spacings of 7 and 14, got: %v", repeats[0].Spacings)
+	}
+	if factors[0].Factor != 7 || factors[0].Count != 2 {
+		t.Errorf("kasiski() expected 7 to be the top factor, got: %v", factors[0])
+	}
+}
+
+func TestAnalyzeVigenere(t *testing.T) {
+	encoded, _ := vigenereShiftSteps("lemon", solverTestText, false, false)
+
+	report := analyzeVigenere(encoded, 0)
+	if report.KeyLength != 5 {
+		t.Errorf("analyzeVigenere() expected a key length of 5, got: %v", report.KeyLength)
+	}
+	if report.Key != "lemon" || report.Unsure {
+		t.Errorf("analyzeVigenere() expected to be sure of the key lemon, got: %s %v", report.Key, report.Columns)
+	}
+	if report.Plaintext != solverTestText {
+		t.Errorf("analyzeVigenere() did not decode the message: %s", report.Plaintext)
+	}
+
+	// a chosen key length is used even if it's wrong
+	report = analyzeVigenere(encoded, 3)
+	if report.KeyLength != 3 || len(report.Columns) != 3 {
+		t.Errorf("analyzeVigenere() expected to use a key length of 3, got: %v", report.KeyLength)
+	}
+
+	// with only a few letters in each column some key letters are a close
+	// call, those get flagged instead of passed off as certain
+	encoded, _ = vigenereShiftSteps("secretkey", solverTestText[:300], false, false)
+	report = analyzeVigenere(encoded, 9)
+	if !report.Unsure {
+		t.Errorf("analyzeVigenere() expected some columns to be unsure, got: %v", report.Columns)
+	}
+	for i, column := range report.Columns {
+		if column.KeyLetter != string("secretkey"[i]) && !column.Unsure {
+			t.Errorf("analyzeVigenere() got column %v wrong but was sure of it: %v", column.Column, column)
+		}
+	}
+
+	if analyzeVigenere("!!!", 0) != nil {
+		t.Errorf("analyzeVigenere() expected nothing back with no letters")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Workspace is a student's attempt at solving a cryptogram by hand.
+// Guesses maps each symbol in the ciphertext to the letter they think it
+// stands for, or "" if they haven't guessed yet.
+type Workspace struct {
+	Ciphertext string
+	Guesses    map[string]string
+	Words      [][]WorkspaceChar
+	Conflicts  []string
+	Solved     int
+	Symbols    int
+}
+
+// WorkspaceChar is one character of the ciphertext with the guess for it
+// underneath. Symbol is false for punctuation that passes straight through.
+type WorkspaceChar struct {
+	Cipher string
+	Plain  string
+	Symbol bool
+}
+
+func isCipherSymbol(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char)
+}
+
+// newWorkspace works out the symbols in the ciphertext and keeps any guesses
+// for them, dropping guesses for symbols that aren't there
+func newWorkspace(ciphertext string, guesses map[string]string) *Workspace {
+	ciphertext = strings.ToLower(ciphertext)
+	workspace := &Workspace{
+		Ciphertext: ciphertext,
+		Guesses:    make(map[string]string),
+	}
+	for _, char := range ciphertext {
+		if isCipherSymbol(char) {
+			workspace.Guesses[string(char)] = guesses[string(char)]
+		}
+	}
+	workspace.render()
+	return workspace
+}
+
+// guess sets what a symbol stands for, an empty guess clears it
+func (workspace *Workspace) guess(symbol string, plain string) {
+	symbol = strings.ToLower(strings.TrimSpace(symbol))
+	if _, ok := workspace.Guesses[symbol]; !ok {
+		return
+	}
+	plain = strings.ToLower(strings.TrimSpace(plain))
+	if len([]rune(plain)) > 1 {
+		plain = string([]rune(plain)[:1])
+	}
+	workspace.Guesses[symbol] = plain
+}
+
+// render fills in the partial plaintext, and points out any letter that has
+// been guessed for more than one symbol since that can't be right
+func (workspace *Workspace) render() {
+	workspace.Words = nil
+	for _, word := range strings.Fields(workspace.Ciphertext) {
+		chars := []WorkspaceChar{}
+		for _, char := range word {
+			wsChar := WorkspaceChar{Cipher: string(char), Plain: string(char)}
+			if isCipherSymbol(char) {
+				wsChar.Symbol = true
+				wsChar.Plain = workspace.Guesses[string(char)]
+			}
+			chars = append(chars, wsChar)
+		}
+		workspace.Words = append(workspace.Words, chars)
+	}
+
+	usedBy := make(map[string][]string)
+	workspace.Solved = 0
+	for symbol, plain := range workspace.Guesses {
+		if plain != "" {
+			usedBy[plain] = append(usedBy[plain], symbol)
+			workspace.Solved++
+		}
+	}
+	workspace.Symbols = len(workspace.Guesses)
+
+	workspace.Conflicts = nil
+	for plain, symbols := range usedBy {
+		if len(symbols) > 1 {
+			sort.Strings(symbols)
+			workspace.Conflicts = append(workspace.Conflicts, plain+" is guessed for "+strings.Join(symbols, ", "))
+		}
+	}
+	sort.Strings(workspace.Conflicts)
+}
+
+func getSessionWorkspace(db *sql.DB, session string, path string) (*Workspace, error) {
+	stmt, err := db.Prepare("select ciphertext, guesses from workspaces where session = ? and path = ?")
+	if err != nil {
+		return newWorkspace("", nil), err
+	}
+	defer stmt.Close()
+
+	var ciphertext, guessesDB string
+	err = stmt.QueryRow(session, path).Scan(&ciphertext, &guessesDB)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return newWorkspace("", nil), nil
+		}
+		return newWorkspace("", nil), err
+	}
+
+	var guesses map[string]string
+	if err = json.Unmarshal([]byte(guessesDB), &guesses); err != nil {
+		return newWorkspace("", nil), err
+	}
+
+	return newWorkspace(ciphertext, guesses), nil
+}
+
+func setSessionWorkspace(db *sql.DB, session string, path string, workspace *Workspace) error {
+	b, err := json.Marshal(workspace.Guesses)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`insert into workspaces(session, path, ciphertext, guesses) values(?, ?, ?, ?)
+		on conflict(session, path) do update set ciphertext = excluded.ciphertext, guesses = excluded.guesses`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(session, path, workspace.Ciphertext, string(b)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
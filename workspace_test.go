@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestWorkspaceGuess(t *testing.T) {
+	workspace := newWorkspace("GSV XZG, gsv wlt!", nil)
+	if workspace.Symbols != 8 {
+		t.Errorf("newWorkspace() expected 8 symbols, got: %v", workspace.Symbols)
+	}
+
+	workspace.guess("g", "T")
+	workspace.guess("s", "h")
+	workspace.guess("?", "x")
+	workspace.render()
+
+	if workspace.Solved != 2 {
+		t.Errorf("guess() expected 2 guesses, got: %v", workspace.Solved)
+	}
+	if _, ok := workspace.Guesses["?"]; ok {
+		t.Errorf("guess() should not add symbols that aren't in the message")
+	}
+
+	first := workspace.Words[0]
+	if first[0].Plain != "t" || first[1].Plain != "h" || first[2].Plain != "" {
+		t.Errorf("render() expected th_, got: %v", first)
+	}
+	// punctuation passes straight through
+	comma := workspace.Words[1][3]
+	if comma.Symbol || comma.Plain != "," {
+		t.Errorf("render() expected the comma to pass through, got: %v", comma)
+	}
+
+	workspace.guess("x", "t")
+	workspace.render()
+	if len(workspace.Conflicts) != 1 {
+		t.Errorf("render() expected a conflict for t, got: %v", workspace.Conflicts)
+	}
+}
+
+func TestSessionWorkspace(t *testing.T) {
+	testDB := setupTestDB(t)
+
+	workspace, err := getSessionWorkspace(testDB, "session1", "testpath")
+	if err != nil {
+		t.Errorf("error in getSessionWorkspace(): %s", err)
+	}
+	if workspace.Ciphertext != "" {
+		t.Errorf("getSessionWorkspace() expected an empty workspace, got: %s", workspace.Ciphertext)
+	}
+
+	workspace = newWorkspace("gsv", nil)
+	workspace.guess("g", "t")
+	if err := setSessionWorkspace(testDB, "session1", "testpath", workspace); err != nil {
+		t.Errorf("error in setSessionWorkspace(): %s", err)
+	}
+	workspace.guess("s", "h")
+	if err := setSessionWorkspace(testDB, "session1", "testpath", workspace); err != nil {
+		t.Errorf("error in setSessionWorkspace(): %s", err)
+	}
+
+	workspace, err = getSessionWorkspace(testDB, "session1", "testpath")
+	if err != nil {
+		t.Errorf("error in getSessionWorkspace(): %s", err)
+	}
+	if workspace.Guesses["g"] != "t" || workspace.Guesses["s"] != "h" {
+		t.Errorf("getSessionWorkspace() expected g=t s=h, got: %v", workspace.Guesses)
+	}
+
+	// other sessions don't see it
+	workspace, _ = getSessionWorkspace(testDB, "session2", "testpath")
+	if workspace.Ciphertext != "" {
+		t.Errorf("getSessionWorkspace() expected an empty workspace, got: %s", workspace.Ciphertext)
+	}
+}